@@ -0,0 +1,133 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+// ErrWebsocketDisconnected is returned by Call when the websocket disconnects
+// before the response is received
+var ErrWebsocketDisconnected = errors.New("Websocket disconnected")
+
+// websocketCallResult is the outcome of a pending websocket request
+type websocketCallResult struct {
+	response *WebsocketResponse
+	err      error
+}
+
+// websocketPendingSet holds the requests waiting for a response, keyed by
+// request ID
+type websocketPendingSet struct {
+	sync.Mutex
+	set map[string]chan websocketCallResult
+}
+
+// add registers a pending request, returning the channel its result is sent to
+func (ps *websocketPendingSet) add(requestID string) chan websocketCallResult {
+	ps.Lock()
+	defer ps.Unlock()
+	if ps.set == nil {
+		ps.set = make(map[string]chan websocketCallResult)
+	}
+	result := make(chan websocketCallResult, 1)
+	ps.set[requestID] = result
+	return result
+}
+
+func (ps *websocketPendingSet) remove(requestID string) {
+	ps.Lock()
+	defer ps.Unlock()
+	delete(ps.set, requestID)
+}
+
+// deliver sends a response to the request waiting for it, returning false if
+// there is none
+func (ps *websocketPendingSet) deliver(r *WebsocketResponse) bool {
+	ps.Lock()
+	defer ps.Unlock()
+	result, ok := ps.set[r.RequestID]
+	if !ok {
+		return false
+	}
+	delete(ps.set, r.RequestID)
+	result <- websocketCallResult{response: r}
+	return true
+}
+
+// fail fails all pending requests with an error
+func (ps *websocketPendingSet) fail(err error) {
+	ps.Lock()
+	defer ps.Unlock()
+	for id, result := range ps.set {
+		delete(ps.set, id)
+		result <- websocketCallResult{err: err}
+	}
+}
+
+// Call sends a websocket request and waits for the response.  Conversations
+// and messages in the response data are decoded as *Conversation and
+// *common.Message.  If the request fails the Layer error is returned as a
+// common.RequestError along with the response.  Call starts reading the
+// websocket if it is not already being read, until the websocket is closed,
+// and stops waiting when the context is cancelled, if the websocket disconnects, or with ErrTimedOut at the
+// context deadline or after 30 seconds.
+func (w *Websocket) Call(ctx context.Context, method, objectID string, data interface{}) (*WebsocketResponse, error) {
+	ctx, span := w.client.startSpan(ctx, "client.Websocket.Call")
+	defer span.End()
+
+	reqID := newRequestID()
+	span.SetAttribute(common.SpanAttributeWebsocketMethod, method)
+	span.SetAttribute(common.SpanAttributeRequestID, reqID)
+
+	packet := &WebsocketPacket{
+		Type: "request",
+		Body: WebsocketRequest{
+			Method:    method,
+			RequestID: reqID,
+			ObjectID:  objectID,
+			Data:      data,
+		},
+	}
+
+	result := w.pending.add(reqID)
+	defer w.pending.remove(reqID)
+
+	w.startReading()
+
+	timer := getTimer(ctx)
+	defer timer.Stop()
+
+	if err := w.Send(ctx, packet); err != nil {
+		err = fmt.Errorf("Error sending %s request: %w", method, err)
+		span.SetError(err)
+		return nil, err
+	}
+
+	select {
+	case r := <-result:
+		if r.err != nil {
+			span.SetError(r.err)
+			return nil, r.err
+		}
+		if reqErr, ok := r.response.Data.(common.RequestError); ok {
+			span.SetError(reqErr)
+			return r.response, reqErr
+		}
+		return r.response, nil
+	case <-ctx.Done():
+		err := ctx.Err()
+		if err == context.DeadlineExceeded {
+			err = ErrTimedOut
+		}
+		span.SetError(err)
+		return nil, err
+	case <-timer.C:
+		span.SetError(ErrTimedOut)
+		return nil, ErrTimedOut
+	}
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+	"github.com/layerhq/go-client/option"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/context"
+)
+
+// newTestCallServer serves a websocket answering requests with the response
+// returned by respond.  An empty response leaves the request unanswered.
+func newTestCallServer(respond func(*WebsocketRequest) string) *httptest.Server {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var p struct {
+				Body *WebsocketRequest `json:"body"`
+			}
+			if err := conn.ReadJSON(&p); err != nil {
+				return
+			}
+			if res := respond(p.Body); res != "" {
+				conn.WriteMessage(websocket.TextMessage, []byte(res))
+			}
+		}
+	}))
+}
+
+func newTestCallClient(t *testing.T, s *httptest.Server) *Client {
+	u, _ := url.Parse(s.URL)
+	wu, _ := url.Parse("ws" + strings.TrimPrefix(s.URL, "http"))
+	c, err := NewClient(
+		context.Background(),
+		"24f43c32-4d95-11e4-b3a2-0fd00000020d",
+		option.WithSessionToken("token"),
+		option.OverrideURL(u),
+		option.WithWebsocketURL(wu),
+		option.WithWebsocketReconnect(&common.RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestWebsocketCall(t *testing.T) {
+	s := newTestCallServer(func(req *WebsocketRequest) string {
+		if req.Method != WebsocketMethodConversationCreate {
+			return fmt.Sprintf(`{"type":"response","body":{"request_id":"%s","method":"%s","success":false,
+				"data":{"id":"invalid_request_id","code":105,"message":"Unknown method"}}}`, req.RequestID, req.Method)
+		}
+		return fmt.Sprintf(`{"type":"response","body":{"request_id":"%s","method":"%s","success":true,
+			"data":{"id":"layer:///conversations/1"}}}`, req.RequestID, req.Method)
+	})
+	defer s.Close()
+
+	c := newTestCallClient(t, s)
+	defer c.Websocket.Close()
+
+	// Concurrent calls each receive their own response
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			conversation, err := c.CreateConversation(context.Background(), []string{"a", "b"}, false, nil)
+			if err == nil && conversation.ID != "layer:///conversations/1" {
+				err = fmt.Errorf("Expected conversation 1, got %s", conversation.ID)
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Failed requests return the Layer error
+	_, err := c.Websocket.Call(context.Background(), "Unknown.method", "", nil)
+	var reqErr common.RequestError
+	if !errors.As(err, &reqErr) || reqErr.ID != "invalid_request_id" {
+		t.Fatalf("Expected a Layer error, got %v", err)
+	}
+}
+
+func TestWebsocketCallTimeout(t *testing.T) {
+	s := newTestCallServer(func(req *WebsocketRequest) string {
+		return ""
+	})
+	defer s.Close()
+
+	c := newTestCallClient(t, s)
+	defer c.Websocket.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Websocket.Call(ctx, WebsocketMethodCounterRead, "", nil); err != ErrTimedOut {
+		t.Fatalf("Expected the request to time out, got %v", err)
+	}
+
+	c.Websocket.pending.Lock()
+	pending := len(c.Websocket.pending.set)
+	c.Websocket.pending.Unlock()
+	if pending != 0 {
+		t.Fatalf("Expected no pending requests, got %d", pending)
+	}
+}
+
+func TestWebsocketCallDisconnected(t *testing.T) {
+	s := newTestCallServer(func(req *WebsocketRequest) string {
+		return ""
+	})
+	defer s.Close()
+
+	c := newTestCallClient(t, s)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.Websocket.Close()
+	}()
+
+	if _, err := c.Websocket.Call(context.Background(), WebsocketMethodCounterRead, "", nil); !errors.Is(err, ErrWebsocketDisconnected) {
+		t.Fatalf("Expected a disconnected error, got %v", err)
+	}
+}
+
+func TestWebsocketCallFromHandler(t *testing.T) {
+	s := newTestCallServer(func(req *WebsocketRequest) string {
+		return fmt.Sprintf(`{"type":"response","body":{"request_id":"%s","method":"%s","success":true,"data":{}}}`,
+			req.RequestID, req.Method)
+	})
+	defer s.Close()
+
+	c := newTestCallClient(t, s)
+	defer c.Websocket.Close()
+
+	// Responses are dispatched to handlers for the method, which call again
+	errs := make(chan error, 1)
+	c.Websocket.HandleFunc(WebsocketMethodPresenceUpdate, func(w *Websocket, p *WebsocketPacket) {
+		_, err := w.Call(context.Background(), WebsocketMethodCounterRead, "", nil)
+		errs <- err
+	})
+	if err := c.SetPresence(context.Background(), common.PresenceAway); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the call from the handler")
+	}
+}
+
+func TestWebsocketReceiveAfterCall(t *testing.T) {
+	s := newTestCallServer(func(req *WebsocketRequest) string {
+		return fmt.Sprintf(`{"type":"response","body":{"request_id":"%s","method":"%s","success":true,"data":{}}}`,
+			req.RequestID, req.Method)
+	})
+	defer s.Close()
+
+	c := newTestCallClient(t, s)
+	defer c.Websocket.Close()
+
+	if _, err := c.Websocket.Call(context.Background(), WebsocketMethodCounterRead, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A receiver can start after a call has started reading the websocket
+	received := make(chan *WebsocketPacket, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.Websocket.Receive(ctx, func(ctx context.Context, p *WebsocketPacket) {
+			received <- p
+		})
+	}()
+
+	var packet *WebsocketPacket
+	for packet == nil {
+		if _, err := c.Websocket.Call(context.Background(), WebsocketMethodCounterRead, "", nil); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case packet = <-received:
+		case err := <-errs:
+			t.Fatalf("Expected the receiver to run, got %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if r, ok := packet.Body.(*WebsocketResponse); !ok || r.Method != WebsocketMethodCounterRead {
+		t.Fatalf("Expected a %s response, got %+v", WebsocketMethodCounterRead, packet.Body)
+	}
+
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("Expected the receiver to stop, got %v", err)
+	}
+}
+
+func TestWebsocketHandlerRemove(t *testing.T) {
+	w := new(Websocket)
+	called := make(chan int, 2)
+	first := w.HandleFunc(WebsocketEventConnected, func(w *Websocket, p *WebsocketPacket) {
+		called <- 1
+	})
+	w.HandleFunc(WebsocketEventConnected, func(w *Websocket, p *WebsocketPacket) {
+		called <- 2
+	})
+	first.Remove()
+
+	w.handlers.dispatch(w, &WebsocketPacket{Body: &WebsocketConnectionEvent{Type: WebsocketEventConnected}})
+	if n := len(called); n != 1 {
+		t.Fatalf("Expected one handler to be called, got %d", n)
+	}
+	if h := <-called; h != 2 {
+		t.Fatalf("Expected the remaining handler to be called, got handler %d", h)
+	}
+}
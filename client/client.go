@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/layerhq/go-client/common"
 	"github.com/layerhq/go-client/option"
 	"github.com/layerhq/go-client/transport"
 
@@ -21,6 +22,7 @@ type Client struct {
 	websocketURL *url.URL
 	appID        string
 	transport    *transport.HTTPTransport
+	presence     presenceCache
 }
 
 // NonceRequest is the payload used to request a nonce
@@ -30,32 +32,62 @@ type NonceRequest struct {
 
 var ErrTimedOut = errors.New("Operation timed out.")
 
-// NewClient creates a new Layer Client API Client
+// Default Client API endpoints and handshake settings, which can be changed
+// with options
+const (
+	DefaultBaseURL              = "https://api.layer.com"
+	DefaultWebsocketURL         = "wss://websockets.layer.com"
+	DefaultAPIVersion           = "2.0"
+	DefaultWebsocketOrigin      = "http://local.host:80"
+	DefaultWebsocketSubprotocol = "layer-3.0"
+)
+
+// NewClient creates a new Layer Client API Client.  The endpoints default to
+// the Layer hosted API, and can be changed with option.OverrideURL and
+// option.WithWebsocketURL.
 func NewClient(ctx context.Context, appID string, options ...option.ClientOption) (*Client, error) {
-	u, err := url.Parse("https://api.layer.com")
-	if err != nil {
-		return nil, fmt.Errorf("Error building base URL: %v", err)
+	settings := option.NewDialSettings(options...)
+
+	u := settings.BaseURL
+	if u == nil {
+		var err error
+		if u, err = url.Parse(DefaultBaseURL); err != nil {
+			return nil, fmt.Errorf("Error building base URL: %v", err)
+		}
 	}
 
-	wu, err := url.Parse("wss://websockets.layer.com")
-	if err != nil {
-		return nil, fmt.Errorf("Error building websocket URL: %v", err)
+	wu := settings.WebsocketURL
+	if wu == nil {
+		var err error
+		if wu, err = url.Parse(DefaultWebsocketURL); err != nil {
+			return nil, fmt.Errorf("Error building websocket URL: %v", err)
+		}
 	}
 
-	return NewClientWithURLs(u, wu, ctx, appID, options...)
+	return newClient(ctx, u, wu, appID, settings)
 }
 
 // NewClientWithURLs creates a new Layer Client API client with custom
 // endpoint URLs.  Most users will not ever need to use this functionality.
 func NewClientWithURLs(u *url.URL, wu *url.URL, ctx context.Context, appID string, options ...option.ClientOption) (*Client, error) {
+	return newClient(ctx, u, wu, appID, option.NewDialSettings(options...))
+}
+
+// newClient creates a Client API client from dial settings built once from
+// the caller's options
+func newClient(ctx context.Context, u *url.URL, wu *url.URL, appID string, settings *common.DialSettings) (*Client, error) {
+	version := settings.APIVersion
+	if version == "" {
+		version = DefaultAPIVersion
+	}
 	headers := map[string][]string{
-		"Accept":       {"application/vnd.layer+json; version=2.0"},
+		"Accept":       {fmt.Sprintf("application/vnd.layer+json; version=%s", version)},
 		"Content-Type": {"application/json"},
 	}
+	option.WithDefaultHeaders(headers).Apply(settings)
+	appID = settings.ApplicationID(appID)
 
-	options = append(options, option.WithHeaders(headers))
-
-	t, err := transport.NewHTTPTransport(ctx, appID, u, wu, options...)
+	t, err := transport.NewHTTPTransportWithSettings(ctx, appID, u, wu, settings)
 	if err != nil {
 		return nil, err
 	}
@@ -66,10 +98,7 @@ func NewClientWithURLs(u *url.URL, wu *url.URL, ctx context.Context, appID strin
 		appID:        appID,
 		transport:    t,
 	}
-	c.Websocket = ws
-	if c.Websocket == nil {
-		c.Websocket = new(Websocket)
-	}
+	c.Websocket = new(Websocket)
 	c.Websocket.client = c
 
 	return c, nil
@@ -95,6 +124,35 @@ func (c *Client) SessionToken(ctx context.Context) (string, error) {
 	return c.transport.Session.Token(ctx)
 }
 
+// websocketHeaders returns the websocket handshake headers
+func (c *Client) websocketHeaders() http.Header {
+	settings := c.transport.DialSettings()
+
+	origin := settings.WebsocketOrigin
+	if origin == "" {
+		origin = DefaultWebsocketOrigin
+	}
+	protocol := settings.WebsocketSubprotocol
+	if protocol == "" {
+		protocol = DefaultWebsocketSubprotocol
+	}
+
+	return http.Header{
+		"Origin":                 {origin},
+		"Sec-WebSocket-Protocol": {protocol},
+	}
+}
+
+// startSpan starts a tracing span for a Client API operation
+func (c *Client) startSpan(ctx context.Context, operation string) (context.Context, common.Span) {
+	return common.StartSpan(ctx, c.transport.DialSettings().Tracer, operation)
+}
+
+// logger returns the logger configured with option.WithLogger
+func (c *Client) logger() common.Logger {
+	return common.RedactLogger(c.transport.DialSettings().Logger)
+}
+
 func newRequestID() string {
 	return uuid.Must(uuid.NewV1()).String()
 }
@@ -93,6 +93,14 @@ func createTokenTestClient() (*Client, error) {
 	)
 }
 
+// skipWithoutCredentials skips tests against the Layer API when no testing
+// credentials are configured
+func skipWithoutCredentials(t *testing.T) {
+	if os.Getenv("LAYER_TESTING_CREDENTIALS") == "" {
+		t.Skip("LAYER_TESTING_CREDENTIALS path is not set")
+	}
+}
+
 func createTestClient() (*Client, error) {
 	// Load credentials
 	path := os.Getenv("LAYER_TESTING_CREDENTIALS")
@@ -125,6 +133,8 @@ func createTestClient() (*Client, error) {
 }
 
 func TestCreateClientWithCredentials(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	_, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -141,6 +151,8 @@ func TestCreateClientWithTokenFunc(t *testing.T) {
 }
 
 func TestAuhenticatedGet(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -217,7 +229,7 @@ func TestCachedToken(t *testing.T) {
 
 func ExampleNewClient() {
 	ctx := context.Background()
-	return NewClient(ctx, "APP_ID", option.WithCredentials(&common.ClientCredentials{User: "USERNAME"}), option.WithTokenFunc(func(user, nonce string) (token string, err error) {
+	c, err := NewClient(ctx, "APP_ID", option.WithCredentials(&common.ClientCredentials{User: "USERNAME"}), option.WithTokenFunc(func(user, nonce string) (token string, err error) {
 		// Make an HTTP call or perform local logic to create a signed JWT
 		// with your private key.
 		//
@@ -225,4 +237,9 @@ func ExampleNewClient() {
 		//   https://docs.layer.com/reference/client_api/authentication.out
 		return
 	}))
+	if err != nil {
+		fmt.Println(fmt.Sprintf("Error creating client: %v", err))
+		return
+	}
+	fmt.Println(c.BaseURL())
 }
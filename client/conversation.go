@@ -9,9 +9,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"time"
 
-	"github.com/layerhq/common"
+	"github.com/layerhq/go-client/common"
 
 	"github.com/layerhq/go-client/iterator"
 )
@@ -38,6 +37,7 @@ func (c *Client) buildConversationURL(id string) (*url.URL, error) {
 
 // Internal request to create a conversation
 type conversationCreate struct {
+	ID           string      `json:"id,omitempty"`
 	Participants []string    `json:"participants"`
 	Distinct     bool        `json:"distinct"`
 	Metadata     interface{} `json:"metadata,omitempty"`
@@ -85,6 +85,9 @@ func (it *ConversationIterator) Next() (*Conversation, error) {
 
 // Conversations gets all conversations for the user specified by the Client connection, with a starting ID used for paging and iterations
 func (c *Client) ConversationsFrom(ctx context.Context, sort string, from string) ([]*Conversation, error) {
+	ctx, span := c.startSpan(ctx, "client.ConversationsFrom")
+	defer span.End()
+
 	// Create the request URL
 	u, err := c.buildConversationURL("")
 	if err != nil {
@@ -110,16 +113,12 @@ func (c *Client) ConversationsFrom(ctx context.Context, sort string, from string
 	// Send the request
 	res, err := c.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error sending conversation request: %v", err)
+		return nil, fmt.Errorf("Error sending conversation request: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusConflict {
-		return nil, fmt.Errorf("Partially matching distinct conversation")
-	}
-
 	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		return nil, common.ResponseError(res)
 	}
 
 	// Parse the body
@@ -158,6 +157,9 @@ func (c *Client) Conversations(ctx context.Context, sort string) (*ConversationI
 
 // Conversation gets a single conversation for the user specified by the Client connection
 func (c *Client) Conversation(ctx context.Context, id string) (*Conversation, error) {
+	ctx, span := c.startSpan(ctx, "client.Conversation")
+	defer span.End()
+
 	u, err := c.buildConversationURL(id)
 	if err != nil {
 		return nil, fmt.Errorf("Error building conversation URL: %v", err)
@@ -173,16 +175,12 @@ func (c *Client) Conversation(ctx context.Context, id string) (*Conversation, er
 	// Send the request
 	res, err := c.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error creating conversation: %v", err)
+		return nil, fmt.Errorf("Error creating conversation: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("Conversation not found")
-	}
-
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		return nil, common.ResponseError(res)
 	}
 
 	// Parse the body
@@ -199,75 +197,58 @@ func (c *Client) Conversation(ctx context.Context, id string) (*Conversation, er
 	return conversation, nil
 }
 
-// CreateConversation creates a conversation over the websocket interface
-func (c *Client) CreateConversation(ctx context.Context, participants []string, distinct bool, metadata interface{}) (*Conversation, error) {
+// CreateConversation creates a conversation over the websocket interface.
+// The conversation ID can be set with common.WithID, making retries with the
+// same ID safe.
+func (c *Client) CreateConversation(ctx context.Context, participants []string, distinct bool, metadata interface{}, opts ...common.CreateOption) (*Conversation, error) {
+	ctx, span := c.startSpan(ctx, "client.CreateConversation")
+	defer span.End()
+
 	// Create the request object
+	settings := common.NewCreateSettings(opts...)
 	cc := &conversationCreate{
+		ID:           common.LayerURL(common.ConversationsName, settings.ID),
 		Participants: participants,
 		Distinct:     distinct,
 		Metadata:     metadata,
 	}
 
-	// Generate a request ID
-	reqID := newRequestID()
-
-	// Build the websocket packet
-	packet := &WebsocketPacket{
-		Type: "request",
-		Body: WebsocketRequest{
-			Method:    WebsocketChangeConversationCreate,
-			RequestID: reqID,
-			Data:      cc,
-		},
-	}
-
-	result := make(chan *Conversation)
-	errs := make(chan error, 2)
+	resp, err := c.Websocket.Call(ctx, WebsocketMethodConversationCreate, "", cc)
+	if reqErr, ok := err.(common.RequestError); ok {
+		reqErr = c.conflictError(reqErr)
 
-	// Register a websocket handler
-	unsub := c.Websocket.HandleFunc(WebsocketChangeConversationCreate, func(w *Websocket, p *WebsocketPacket) {
-		resp, ok := p.Body.(*WebsocketResponse)
-		if !ok || resp.RequestID != reqID {
-			return
+		// A conversation created by an earlier attempt of the same request
+		if existing, ok := reqErr.Data.(*Conversation); ok && existing.CreatedWith(settings.ID, participants, distinct) {
+			return existing, nil
 		}
-
-		conversation, ok := resp.Data.(*Conversation)
-		if !ok {
-			errs <- errors.New("Cannot convert response to Conversation.")
-			return
-		}
-		result <- conversation
-	})
-	defer unsub.Remove()
-	go func() {
-		if err := c.Websocket.Listen(ctx); err != nil {
-			errs <- err
-		}
-	}()
-
-	timer := getTimer(ctx)
-
-	// Send the packet
-	if err := c.Websocket.Send(ctx, packet); err != nil {
+		err = reqErr
+	}
+	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 
-	// Wait for the reply or timeout
-	var conversation *Conversation
-	select {
-	case conversation = <-result:
-		return conversation, nil
-	case err := <-errs:
+	conversation, ok := resp.Data.(*Conversation)
+	if !ok {
+		err = errors.New("Cannot convert response to Conversation.")
+		span.SetError(err)
 		return nil, err
-	case <-timer.C:
-		return nil, ErrTimedOut
 	}
+	conversation.Client = c
+	return conversation, nil
 }
 
-// CreateConversationREST creates a conversation over the REST API interface
-func (c *Client) CreateConversationREST(ctx context.Context, participants []string, distinct bool, metadata interface{}) (*Conversation, error) {
+// CreateConversationREST creates a conversation over the REST API interface.
+// The conversation ID can be set with common.WithID, making retries with the
+// same ID safe.
+func (c *Client) CreateConversationREST(ctx context.Context, participants []string, distinct bool, metadata interface{}, opts ...common.CreateOption) (*Conversation, error) {
+	ctx, span := c.startSpan(ctx, "client.CreateConversationREST")
+	defer span.End()
+
 	// Create the request object
+	settings := common.NewCreateSettings(opts...)
 	cc := &conversationCreate{
+		ID:           common.LayerURL(common.ConversationsName, settings.ID),
 		Participants: participants,
 		Distinct:     distinct,
 		Metadata:     metadata,
@@ -289,24 +270,29 @@ func (c *Client) CreateConversationREST(ctx context.Context, participants []stri
 		return nil, fmt.Errorf("Error creating conversation request: %v", err)
 	}
 	req = req.WithContext(ctx)
+	req.Header.Set(common.IdempotencyKeyHeader, settings.ID)
 
 	// Send the request
 	res, err := c.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error creating conversation: %v", err)
+		return nil, fmt.Errorf("Error creating conversation: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusConflict {
-		return nil, fmt.Errorf("Partially matching distinct conversation")
-	}
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		reqErr := c.conflictError(common.ResponseError(res))
 
-	if res.StatusCode == http.StatusUnprocessableEntity {
-		return nil, fmt.Errorf("Participant blocked")
-	}
+		// A conversation created by an earlier attempt of the same request
+		if existing, ok := reqErr.Data.(*Conversation); ok && existing.CreatedWith(settings.ID, participants, distinct) {
+			return existing, nil
+		}
 
-	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		// Conversations with a participant who has blocked the user are
+		// rejected as unprocessable
+		if res.StatusCode == http.StatusUnprocessableEntity && reqErr.ID == "" {
+			reqErr.ID = "participant_blocked"
+		}
+		return nil, reqErr
 	}
 
 	// Parse the body
@@ -323,11 +309,29 @@ func (c *Client) CreateConversationREST(ctx context.Context, participants []stri
 	return conversation, nil
 }
 
+// conflictError sets the conflicting conversation returned with a conflict
+// error as the error data
+func (c *Client) conflictError(reqErr common.RequestError) common.RequestError {
+	if !reqErr.Is(common.ErrConflict) {
+		return reqErr
+	}
+
+	var conversation *Conversation
+	if err := reqErr.DecodeData(&conversation); err == nil && conversation != nil {
+		conversation.Client = c
+		reqErr.Data = conversation
+	}
+	return reqErr
+}
+
 // Delete removes a conversation, with an optional mode of "all_participants" to
 // remove from all participant devices or "my_devices" to only remove from the
 // active users devices.  The "leave" boolean specifies if the current user
 // should leave the conversation, and is only applicable for a mode of "my_devices".
 func (convo *Conversation) Delete(ctx context.Context, mode *string, leave bool) error {
+	ctx, span := convo.Client.startSpan(ctx, "client.Conversation.Delete")
+	defer span.End()
+
 	// Create the request URL
 	u, err := convo.Client.buildConversationURL(convo.ID)
 	if err != nil {
@@ -354,20 +358,12 @@ func (convo *Conversation) Delete(ctx context.Context, mode *string, leave bool)
 	// Send the request
 	res, err := convo.Client.transport.Do(req)
 	if err != nil {
-		return fmt.Errorf("Error deleting conversation: %v", err)
+		return fmt.Errorf("Error deleting conversation: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("Conversation not found")
-	}
-
-	if res.StatusCode == http.StatusForbidden {
-		return fmt.Errorf("Forbidden")
-	}
-
 	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
-		return fmt.Errorf("Status code is %d", res.StatusCode)
+		return common.ResponseError(res)
 	}
 
 	return nil
@@ -1,15 +1,20 @@
 package client
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/layerhq/go-client/common"
 	"github.com/layerhq/go-client/iterator"
 
 	"golang.org/x/net/context"
 )
 
 func TestCreateConversation(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -25,6 +30,8 @@ func TestCreateConversation(t *testing.T) {
 }
 
 func TestGetConversations(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -48,6 +55,8 @@ func TestGetConversations(t *testing.T) {
 }
 
 func TestGetConversationsSortedByCreatedAt(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -71,6 +80,8 @@ func TestGetConversationsSortedByCreatedAt(t *testing.T) {
 }
 
 func TestGetConversationsSortedByLastMessage(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -92,3 +103,42 @@ func TestGetConversationsSortedByLastMessage(t *testing.T) {
 		fmt.Println(fmt.Sprintf("%+v", convo))
 	}
 }
+
+func TestCreateConversationWebsocketRetry(t *testing.T) {
+	// Layer reports an existing client-supplied ID as id_in_use and an
+	// existing distinct conversation as conflict
+	for _, errID := range []string{"id_in_use", "conflict"} {
+		s := newTestCallServer(func(req *WebsocketRequest) string {
+			data, _ := json.Marshal(req.Data)
+			var cc conversationCreate
+			json.Unmarshal(data, &cc)
+			return fmt.Sprintf(`{"type":"response","body":{"request_id":"%s","method":"%s","success":false,
+				"data":{"id":"%s","code":111,"message":"The requested Conversation already exists",
+				"data":{"id":"%s","distinct":false,"participants":[{"user_id":"a"},{"user_id":"b"}]}}}}`,
+				req.RequestID, req.Method, errID, cc.ID)
+		})
+
+		c := newTestCallClient(t, s)
+		id := "11111111-2222-4333-8444-555555555555"
+		convo, err := c.CreateConversation(context.Background(), []string{"a", "b"}, false, nil, common.WithID(id))
+		if err != nil {
+			t.Fatalf("Expected the existing conversation for %s, got %v", errID, err)
+		}
+		if common.UUIDFromLayerURL(convo.ID) != id || convo.Client != c {
+			t.Fatalf("Expected the existing conversation for %s, got %+v", errID, convo)
+		}
+
+		// A conflict with different participants is still an error
+		_, err = c.CreateConversation(context.Background(), []string{"other"}, false, nil, common.WithID(id))
+		var reqErr common.RequestError
+		if !errors.Is(err, common.ErrConflict) || !errors.As(err, &reqErr) {
+			t.Fatalf("Expected a conflict error for %s, got %v", errID, err)
+		}
+		if existing, ok := reqErr.Data.(*Conversation); !ok || existing.Client != c {
+			t.Fatalf("Expected the conflicting conversation for %s, got %+v", errID, reqErr.Data)
+		}
+
+		c.Websocket.Close()
+		s.Close()
+	}
+}
@@ -4,11 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/layerhq/go-client/common"
 	"github.com/layerhq/go-client/iterator"
@@ -21,74 +21,67 @@ const (
 )
 
 type messageCreate struct {
+	ID           string                      `json:"id,omitempty"`
 	Parts        []*common.MessagePart       `json:"parts"`
 	Notification *common.MessageNotification `json:"notification,omitempty"`
 }
 
 // SendTextMessage is a helper function to send a single-part plaintext message
-func (convo *Conversation) SendTextMessage(ctx context.Context, message string, notification *common.MessageNotification) (*common.Message, error) {
+func (convo *Conversation) SendTextMessage(ctx context.Context, message string, notification *common.MessageNotification, opts ...common.CreateOption) (*common.Message, error) {
 	msg := plaintextMessage(message)
-	return convo.SendMessage(ctx, msg.Parts, notification)
+	return convo.SendMessage(ctx, msg.Parts, notification, opts...)
 }
 
-// SendMessage sends a message on the current conversation
-func (convo *Conversation) SendMessage(ctx context.Context, parts []*MessagePart, notification *common.MessageNotification) (*common.Message, error) {
+// SendMessage sends a message on the current conversation.  The message ID
+// can be set with common.WithID, making retries with the same ID safe.
+func (convo *Conversation) SendMessage(ctx context.Context, parts []*common.MessagePart, notification *common.MessageNotification, opts ...common.CreateOption) (*common.Message, error) {
+	ctx, span := convo.Client.startSpan(ctx, "client.Conversation.SendMessage")
+	defer span.End()
+
+	settings := common.NewCreateSettings(opts...)
 	mc := &messageCreate{
+		ID:           common.LayerURL(common.MessagesName, settings.ID),
 		Parts:        parts,
 		Notification: notification,
 	}
 
-	reqID := newRequestID()
-
-	packet := &WebsocketPacket{
-		Type: "request",
-		Body: WebsocketRequest{
-			Method:    WebsocketMessageCreate,
-			RequestID: reqID,
-			ObjectID:  convo.ID,
-			Data:      mc,
-		},
-	}
-
-	result := make(chan *common.Message)
-
-	// Register a handler for the response
-	unsub := convo.Client.Websocket.HandleFunc(WebsocketMessageCreate, func(w *Websocket, p *WebsocketPacket) {
-		resp, ok := p.Body.(*WebsocketResponse)
-		if !ok || resp.RequestID != reqID {
-			return
+	resp, err := convo.Client.Websocket.Call(ctx, WebsocketMethodMessageCreate, convo.ID, mc)
+	if reqErr, ok := err.(common.RequestError); ok {
+		// A message sent by an earlier attempt of the same request
+		var existing *common.Message
+		if reqErr.Is(common.ErrConflict) && reqErr.DecodeData(&existing) == nil && existing.CreatedWith(settings.ID, parts) {
+			return existing, nil
 		}
-
-		message, _ := resp.Data.(*common.Message)
-		result <- message
-	})
-	defer unsub.Remove()
-	go convo.Client.Websocket.Listen(ctx)
-
-	timer := getTimer(ctx)
-
-	if err := convo.Client.Websocket.Send(ctx, packet); err != nil {
+	}
+	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 
-	var message *common.Message
-	select {
-	case message = <-result:
-		return message, nil
-	case <-timer.C:
-		return nil, ErrTimedOut
+	message, ok := resp.Data.(*common.Message)
+	if !ok {
+		err = errors.New("Cannot convert response to Message.")
+		span.SetError(err)
+		return nil, err
 	}
+	return message, nil
 }
 
 // SendTextMessage is a helper function to send a single-part plaintext message
-func (convo *Conversation) SendTextMessageREST(ctx context.Context, message string, notification *MessageNotification) (*common.Message, error) {
+func (convo *Conversation) SendTextMessageREST(ctx context.Context, message string, notification *common.MessageNotification, opts ...common.CreateOption) (*common.Message, error) {
 	msg := plaintextMessage(message)
-	return convo.SendMessageREST(ctx, msg.Parts, notification)
+	return convo.SendMessageREST(ctx, msg.Parts, notification, opts...)
 }
 
-// SendMessage sends a message on the current conversation
-func (convo *Conversation) SendMessageREST(ctx context.Context, parts []*common.MessagePart, notification *common.MessageNotification) (*common.Message, error) {
+// SendMessage sends a message on the current conversation.  The message ID
+// can be set with common.WithID, making retries with the same ID safe.
+func (convo *Conversation) SendMessageREST(ctx context.Context, parts []*common.MessagePart, notification *common.MessageNotification, opts ...common.CreateOption) (*common.Message, error) {
+	ctx, span := convo.Client.startSpan(ctx, "client.Conversation.SendMessageREST")
+	defer span.End()
+
+	settings := common.NewCreateSettings(opts...)
 	mc := &messageCreate{
+		ID:           common.LayerURL(common.MessagesName, settings.ID),
 		Parts:        parts,
 		Notification: notification,
 	}
@@ -111,19 +104,24 @@ func (convo *Conversation) SendMessageREST(ctx context.Context, parts []*common.
 		return nil, fmt.Errorf("Error creating request: %v", err)
 	}
 	req = req.WithContext(ctx)
+	req.Header.Set(common.IdempotencyKeyHeader, settings.ID)
 
 	// Send the request
 	res, err := convo.Client.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error sending request: %v", err)
+		return nil, fmt.Errorf("Error sending request: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusConflict {
-		return nil, fmt.Errorf("The requested message already exists")
-	}
 	if res.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		reqErr := common.ResponseError(res)
+
+		// A message sent by an earlier attempt of the same request
+		var existing *common.Message
+		if reqErr.Is(common.ErrConflict) && reqErr.DecodeData(&existing) == nil && existing.CreatedWith(settings.ID, parts) {
+			return existing, nil
+		}
+		return nil, reqErr
 	}
 
 	// Parse the body
@@ -186,6 +184,9 @@ func (it *MessageIterator) Next() (*common.Message, error) {
 
 // MessagesFrom gets all messages on a conversation from the specified offset
 func (convo *Conversation) MessagesFrom(ctx context.Context, from string) ([]*common.Message, error) {
+	ctx, span := convo.Client.startSpan(ctx, "client.Conversation.MessagesFrom")
+	defer span.End()
+
 	// Create the request URL
 	convoID := common.UUIDFromLayerURL(convo.ID)
 	u, err := url.Parse(fmt.Sprintf("/conversations/%s/messages", convoID))
@@ -210,12 +211,12 @@ func (convo *Conversation) MessagesFrom(ctx context.Context, from string) ([]*co
 	// Send the request
 	res, err := convo.Client.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error sending request: %v", err)
+		return nil, fmt.Errorf("Error sending request: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		return nil, common.ResponseError(res)
 	}
 
 	var messages []*common.Message
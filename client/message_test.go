@@ -1,11 +1,13 @@
 package client
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/layerhq/go-client/common"
 	"github.com/layerhq/go-client/iterator"
 
 	"golang.org/x/net/context"
@@ -24,7 +26,81 @@ func TestPlaintextMessage(t *testing.T) {
 	}
 }
 
+func TestSendMessageWebsocket(t *testing.T) {
+	s := newTestCallServer(func(req *WebsocketRequest) string {
+		data, _ := json.Marshal(req.Data)
+		var mc messageCreate
+		json.Unmarshal(data, &mc)
+		data, _ = json.Marshal(&common.Message{
+			ID:           mc.ID,
+			Parts:        mc.Parts,
+			Conversation: &common.Conversation{ID: req.ObjectID},
+		})
+		return fmt.Sprintf(`{"type":"response","body":{"request_id":"%s","method":"%s","success":true,"data":%s}}`,
+			req.RequestID, req.Method, data)
+	})
+	defer s.Close()
+
+	c := newTestCallClient(t, s)
+	defer c.Websocket.Close()
+
+	convo := &Conversation{Client: c}
+	convo.ID = "layer:///conversations/1"
+	id := "7b7d0c9e-4d95-11e4-b3a2-0fd00000020d"
+	message, err := convo.SendTextMessage(context.Background(), "Test", nil, common.WithID(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.ID != common.LayerURL(common.MessagesName, id) || message.Conversation.ID != convo.ID {
+		t.Fatalf("Expected message %s in conversation %s, got %+v", id, convo.ID, message)
+	}
+	if len(message.Parts) != 1 || message.Parts[0].Body != "Test" {
+		t.Fatalf("Expected a text message, got %+v", message.Parts)
+	}
+}
+
+func TestSendMessageWebsocketRetry(t *testing.T) {
+	s := newTestCallServer(func(req *WebsocketRequest) string {
+		data, _ := json.Marshal(req.Data)
+		var mc messageCreate
+		json.Unmarshal(data, &mc)
+
+		// Report the message as sent by an earlier attempt
+		data, _ = json.Marshal(&common.Message{
+			ID:           mc.ID,
+			Parts:        []*common.MessagePart{{Body: "Test", MimeType: "text/plain"}},
+			Conversation: &common.Conversation{ID: req.ObjectID},
+		})
+		return fmt.Sprintf(`{"type":"response","body":{"request_id":"%s","method":"%s","success":false,
+			"data":{"id":"id_in_use","code":111,"message":"The requested Message already exists","data":%s}}}`,
+			req.RequestID, req.Method, data)
+	})
+	defer s.Close()
+
+	c := newTestCallClient(t, s)
+	defer c.Websocket.Close()
+
+	convo := &Conversation{Client: c}
+	convo.ID = "layer:///conversations/1"
+	id := "7b7d0c9e-4d95-11e4-b3a2-0fd00000020d"
+	message, err := convo.SendTextMessage(context.Background(), "Test", nil, common.WithID(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.ID != common.LayerURL(common.MessagesName, id) || len(message.Parts) != 1 || message.Parts[0].Body != "Test" {
+		t.Fatalf("Expected the existing message, got %+v", message)
+	}
+
+	// A conflict with different parts is still an error
+	_, err = convo.SendTextMessage(context.Background(), "Other", nil, common.WithID(id))
+	if !errors.Is(err, common.ErrConflict) {
+		t.Fatalf("Expected a conflict error, got %v", err)
+	}
+}
+
 func TestSendTextMessage(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -44,6 +120,8 @@ func TestSendTextMessage(t *testing.T) {
 }
 
 func TestSendTextMessageExistingConversation(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -63,6 +141,8 @@ func TestSendTextMessageExistingConversation(t *testing.T) {
 }
 
 func TestGetMessages(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+// IdentityPresence is the presence of a user identity, dispatched to
+// WebsocketEventPresence handlers when it changes
+type IdentityPresence struct {
+	// ID is the Layer URL of the identity
+	ID       string           `json:"id"`
+	Presence *common.Presence `json:"presence"`
+}
+
+// presenceCache holds the last known presence of identities, keyed by Layer
+// identity URL.  Each change event is numbered so that a sync does not
+// overwrite changes received while it was in flight.
+type presenceCache struct {
+	sync.RWMutex
+	presences map[string]*common.Presence
+	changes   uint64
+	changed   map[string]uint64
+}
+
+func (pc *presenceCache) get(id string) (*common.Presence, bool) {
+	pc.RLock()
+	defer pc.RUnlock()
+	p, ok := pc.presences[id]
+	if !ok {
+		return nil, false
+	}
+	presence := *p
+	return &presence, true
+}
+
+// generation returns the number of change events applied so far
+func (pc *presenceCache) generation() uint64 {
+	pc.RLock()
+	defer pc.RUnlock()
+	return pc.changes
+}
+
+// sync sets the presence of an identity unless a change event has been
+// applied to it since the given generation
+func (pc *presenceCache) sync(id string, p *common.Presence, since uint64) {
+	pc.Lock()
+	defer pc.Unlock()
+	if pc.changed[id] > since {
+		return
+	}
+	if pc.presences == nil {
+		pc.presences = make(map[string]*common.Presence)
+	}
+	pc.presences[id] = p
+}
+
+// patch applies presence changes from an identity update, returning the new
+// presence or nil if the update did not change it
+func (pc *presenceCache) patch(id string, ops []*WebsocketChangeData) *common.Presence {
+	pc.Lock()
+	defer pc.Unlock()
+
+	presence := &common.Presence{}
+	if current, ok := pc.presences[id]; ok {
+		*presence = *current
+	}
+
+	changed := false
+	for _, op := range ops {
+		switch op.Property {
+		case "presence.status":
+			if status, ok := op.Value.(string); ok {
+				presence.Status = status
+				changed = true
+			}
+		case "presence.last_seen_at":
+			if value, ok := op.Value.(string); ok {
+				if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+					presence.LastSeenAt = &t
+					changed = true
+				}
+			}
+		case "presence":
+			data, err := json.Marshal(op.Value)
+			if err == nil && json.Unmarshal(data, presence) == nil {
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if pc.presences == nil {
+		pc.presences = make(map[string]*common.Presence)
+	}
+	if pc.changed == nil {
+		pc.changed = make(map[string]uint64)
+	}
+	pc.presences[id] = presence
+	pc.changes++
+	pc.changed[id] = pc.changes
+	result := *presence
+	return &result
+}
+
+// Presence returns the last known presence of an identity, as synced with
+// SyncPresence and updated by presence change events received on the
+// websocket
+func (c *Client) Presence(id string) (*common.Presence, bool) {
+	return c.presence.get(common.LayerURL(common.IdentitiesName, id))
+}
+
+// SetPresence sets the presence status of the authenticated user
+func (c *Client) SetPresence(ctx context.Context, status string) error {
+	if err := common.ValidatePresenceStatus(status); err != nil {
+		return err
+	}
+
+	_, err := c.Websocket.Call(ctx, WebsocketMethodPresenceUpdate, "", map[string]string{
+		"status": status,
+	})
+	if err != nil {
+		return fmt.Errorf("Error updating presence: %w", err)
+	}
+	return nil
+}
+
+// SyncPresence fetches the presence of identities, given as user IDs or
+// Layer identity URLs, and updates the presence cache.  The result is keyed
+// by Layer identity URL.
+func (c *Client) SyncPresence(ctx context.Context, ids []string) (map[string]*common.Presence, error) {
+	identities := make([]string, len(ids))
+	for i, id := range ids {
+		identities[i] = common.LayerURL(common.IdentitiesName, id)
+	}
+
+	since := c.presence.generation()
+	resp, err := c.Websocket.Call(ctx, WebsocketMethodPresenceSync, "", identities)
+	if err != nil {
+		return nil, fmt.Errorf("Error syncing presence: %w", err)
+	}
+
+	raw, ok := resp.Data.(*json.RawMessage)
+	if !ok {
+		return nil, fmt.Errorf("Error parsing presence sync response")
+	}
+	var synced []*IdentityPresence
+	if err := json.Unmarshal(*raw, &synced); err != nil {
+		return nil, fmt.Errorf("Error parsing presence sync JSON: %v", err)
+	}
+
+	result := make(map[string]*common.Presence)
+	for _, p := range synced {
+		if p.Presence == nil {
+			continue
+		}
+		presence := *p.Presence
+		c.presence.sync(p.ID, &presence, since)
+		result[p.ID] = p.Presence
+	}
+	return result, nil
+}
+
+// OnPresence registers a handler called when the presence of an identity
+// changes
+func (w *Websocket) OnPresence(h func(*Websocket, *IdentityPresence)) WebsocketEventHandlerRemover {
+	return w.HandleFunc(WebsocketEventPresence, func(w *Websocket, p *WebsocketPacket) {
+		if presence, ok := p.Body.(*IdentityPresence); ok {
+			h(w, presence)
+		}
+	})
+}
+
+// receivePresence updates the presence cache from an identity change,
+// dispatching a presence event if the presence has changed
+func (w *Websocket) receivePresence(c *WebsocketChange) {
+	if !strings.EqualFold(c.Object.Type, "identity") {
+		return
+	}
+	ops, ok := c.Data.([]*WebsocketChangeData)
+	if !ok {
+		return
+	}
+
+	presence := w.client.presence.patch(c.Object.ID, ops)
+	if presence == nil {
+		return
+	}
+	w.dispatch(&WebsocketPacket{
+		Type:      "presence",
+		Body:      &IdentityPresence{ID: c.Object.ID, Presence: presence},
+		Timestamp: time.Now(),
+	})
+}
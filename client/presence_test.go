@@ -0,0 +1,116 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/context"
+)
+
+func TestPresence(t *testing.T) {
+	requests := make(chan *WebsocketRequest, 10)
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var p struct {
+				Body *WebsocketRequest `json:"body"`
+			}
+			if err := conn.ReadJSON(&p); err != nil {
+				return
+			}
+			requests <- p.Body
+
+			data := `{}`
+			if p.Body.Method == WebsocketMethodPresenceSync {
+				data = `[
+					{"id": "layer:///identities/a", "presence": {"status": "available", "last_seen_at": "2017-01-01T00:00:00Z"}},
+					{"id": "layer:///identities/b", "presence": {"status": "offline"}}
+				]`
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{
+				"type": "response",
+				"body": {"request_id": "%s", "method": "%s", "success": true, "data": %s}
+			}`, p.Body.RequestID, p.Body.Method, data)))
+
+			// Identity b comes online
+			if p.Body.Method == WebsocketMethodPresenceSync {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{
+					"type": "change",
+					"body": {
+						"operation": "update",
+						"object": {"type": "Identity", "id": "layer:///identities/b"},
+						"data": [{"operation": "set", "property": "presence.status", "value": "busy"}]
+					}
+				}`))
+			}
+		}
+	}))
+	defer s.Close()
+
+	c := newTestCallClient(t, s)
+	defer c.Websocket.Close()
+	changes := make(chan *IdentityPresence, 1)
+	c.Websocket.OnPresence(func(w *Websocket, p *IdentityPresence) {
+		changes <- p
+	})
+
+	ctx := context.Background()
+	if err := c.SetPresence(ctx, "asleep"); err == nil {
+		t.Fatal("Expected an error setting an invalid status")
+	}
+	if err := c.SetPresence(ctx, common.PresenceAway); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+	data, _ := json.Marshal(req.Data)
+	if req.Method != WebsocketMethodPresenceUpdate || string(data) != `{"status":"away"}` {
+		t.Fatalf("Expected a presence update to away, got %s with %s", req.Method, data)
+	}
+
+	presences, err := c.SyncPresence(ctx, []string{"a", "layer:///identities/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = <-requests
+	data, _ = json.Marshal(req.Data)
+	if req.Method != WebsocketMethodPresenceSync || string(data) != `["layer:///identities/a","layer:///identities/b"]` {
+		t.Fatalf("Expected a presence sync for a and b, got %s with %s", req.Method, data)
+	}
+	if len(presences) != 2 || !presences["layer:///identities/a"].Online() {
+		t.Fatalf("Expected a to be online, got %+v", presences)
+	}
+
+	select {
+	case change := <-changes:
+		if change.ID != "layer:///identities/b" || change.Presence.Status != common.PresenceBusy {
+			t.Fatalf("Expected b to be busy, got %s for %s", change.Presence.Status, change.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for presence change")
+	}
+
+	if p, ok := c.Presence("a"); !ok || p.Status != common.PresenceAvailable || p.LastSeenAt == nil {
+		t.Fatalf("Expected a to be cached as available, got %+v", p)
+	}
+	if p, ok := c.Presence("b"); !ok || !p.Online() {
+		t.Fatalf("Expected b to be cached as online, got %+v", p)
+	}
+	if _, ok := c.Presence("c"); ok {
+		t.Fatal("Expected no presence for c")
+	}
+}
@@ -0,0 +1,251 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/context"
+)
+
+// WebsocketGap describes change events that may have been missed, either
+// while the websocket was reconnecting or because the packet counter skipped
+type WebsocketGap struct {
+	// Since is the timestamp of the last packet received before the gap
+	Since time.Time
+
+	// LastCounter and Counter are the packet counters either side of a
+	// counter gap, and zero for a gap caused by reconnecting
+	LastCounter int
+	Counter     int
+
+	// Err is the reason the gap could not be recovered
+	Err error
+}
+
+// websocketRecovery tracks the packet counter and the conversations seen on
+// the websocket so that missed events can be recovered
+type websocketRecovery struct {
+	sync.Mutex
+	conn          *websocket.Conn
+	lastCounter   int
+	lastTimestamp time.Time
+	recovering    bool
+	conversations map[string]struct{}
+}
+
+// observe records a received packet, returning a gap if the packet counter
+// skipped.  Counters restart with each connection.
+func (r *websocketRecovery) observe(conn *websocket.Conn, p *WebsocketPacket) *WebsocketGap {
+	r.Lock()
+	defer r.Unlock()
+
+	var gap *WebsocketGap
+	if p.Counter > 0 {
+		if r.conn == conn && p.Counter != r.lastCounter+1 && !r.recovering {
+			gap = &WebsocketGap{
+				Since:       r.lastTimestamp,
+				LastCounter: r.lastCounter,
+				Counter:     p.Counter,
+			}
+		}
+		r.conn = conn
+		r.lastCounter = p.Counter
+	}
+	if p.Timestamp.After(r.lastTimestamp) {
+		r.lastTimestamp = p.Timestamp
+	}
+
+	switch body := p.Body.(type) {
+	case *WebsocketResponse:
+		if conversation, ok := body.Data.(*Conversation); ok {
+			r.track(conversation.ID)
+		}
+	case *WebsocketChange:
+		switch data := body.Data.(type) {
+		case *Conversation:
+			r.track(data.ID)
+		case *common.Message:
+			if data.Conversation != nil {
+				r.track(data.Conversation.ID)
+			}
+		}
+		if strings.EqualFold(body.Object.Type, "conversation") && strings.EqualFold(body.Operation, "delete") {
+			delete(r.conversations, body.Object.ID)
+		}
+	}
+
+	return gap
+}
+
+// track records a conversation to resync if events are missed
+func (r *websocketRecovery) track(id string) {
+	if id == "" {
+		return
+	}
+	if r.conversations == nil {
+		r.conversations = make(map[string]struct{})
+	}
+	r.conversations[id] = struct{}{}
+}
+
+// reconnected returns the gap caused by reconnecting, if any packets were
+// received before the connection dropped
+func (r *websocketRecovery) reconnected() *WebsocketGap {
+	r.Lock()
+	defer r.Unlock()
+	if r.lastTimestamp.IsZero() {
+		return nil
+	}
+	return &WebsocketGap{Since: r.lastTimestamp}
+}
+
+// start marks a recovery as in progress, returning false if one already is
+func (r *websocketRecovery) start() bool {
+	r.Lock()
+	defer r.Unlock()
+	if r.recovering {
+		return false
+	}
+	r.recovering = true
+	return true
+}
+
+func (r *websocketRecovery) finish() {
+	r.Lock()
+	r.recovering = false
+	r.Unlock()
+}
+
+// tracked returns the conversations seen on the websocket
+func (r *websocketRecovery) tracked() []string {
+	r.Lock()
+	defer r.Unlock()
+	ids := make([]string, 0, len(r.conversations))
+	for id := range r.conversations {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// OnGap registers a handler called when missed events can neither be
+// replayed nor resynced over the REST API
+func (w *Websocket) OnGap(h func(*Websocket, *WebsocketGap)) WebsocketEventHandlerRemover {
+	return w.HandleFunc(WebsocketEventGap, func(w *Websocket, p *WebsocketPacket) {
+		if gap, ok := p.Body.(*WebsocketGap); ok {
+			h(w, gap)
+		}
+	})
+}
+
+// recover requests a replay of the events missed in a gap, falling back to
+// resyncing conversations over the REST API if replay is unavailable
+func (w *Websocket) recover(ctx context.Context, gap *WebsocketGap) {
+	if !w.recovery.start() {
+		return
+	}
+	defer w.recovery.finish()
+
+	logger := w.client.logger()
+	logger.Info("Recovering missed websocket events", "since", gap.Since, "last_counter", gap.LastCounter, "counter", gap.Counter)
+
+	err := w.replay(ctx, gap.Since)
+	if err == nil {
+		return
+	}
+	logger.Info("Websocket event replay failed, resyncing conversations", "error", err)
+
+	if err = w.resync(ctx); err == nil {
+		return
+	}
+	logger.Warn("Error resyncing conversations, websocket events have been lost", "error", err)
+
+	gap.Err = err
+	w.dispatch(&WebsocketPacket{
+		Type:      "gap",
+		Body:      gap,
+		Timestamp: time.Now(),
+	})
+}
+
+// replay requests the change events since a timestamp, which are sent on the
+// websocket before the response
+func (w *Websocket) replay(ctx context.Context, since time.Time) error {
+	_, err := w.Call(ctx, WebsocketMethodEventReplay, "", map[string]string{
+		"from_timestamp": since.UTC().Format(time.RFC3339Nano),
+	})
+	return err
+}
+
+// resync fetches the most recently active conversations and any other
+// conversations seen on the websocket over the REST API, dispatching them as
+// Change.Conversation.resync events.  Conversations that no longer exist are
+// dispatched as Change.Conversation.delete events.
+func (w *Websocket) resync(ctx context.Context) error {
+	conversations, err := w.client.ConversationsFrom(ctx, "last_message", "")
+	if err != nil {
+		return fmt.Errorf("Error resyncing conversations: %w", err)
+	}
+
+	seen := make(map[string]struct{})
+	for _, conversation := range conversations {
+		conversation.Client = w.client
+		seen[conversation.ID] = struct{}{}
+		w.dispatchResync(conversation)
+	}
+
+	for _, id := range w.recovery.tracked() {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+
+		conversation, err := w.client.Conversation(ctx, common.UUIDFromLayerURL(id))
+		if errors.Is(err, common.ErrNotFound) {
+			w.recovery.Lock()
+			delete(w.recovery.conversations, id)
+			w.recovery.Unlock()
+			w.dispatchChange(&WebsocketChange{
+				Operation: "delete",
+				Object:    WebsocketChangeObject{Type: "Conversation", ID: id},
+			})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Error resyncing conversation %s: %w", id, err)
+		}
+		w.dispatchResync(conversation)
+	}
+
+	return nil
+}
+
+// dispatchResync dispatches a conversation fetched over the REST API
+func (w *Websocket) dispatchResync(conversation *Conversation) {
+	w.recovery.Lock()
+	w.recovery.track(conversation.ID)
+	w.recovery.Unlock()
+
+	w.dispatchChange(&WebsocketChange{
+		Operation: "resync",
+		Object: WebsocketChangeObject{
+			Type: "Conversation",
+			ID:   conversation.ID,
+			URL:  conversation.URL,
+		},
+		Data: conversation,
+	})
+}
+
+// dispatchChange dispatches a change event synthesized by the client
+func (w *Websocket) dispatchChange(c *WebsocketChange) {
+	w.dispatch(&WebsocketPacket{
+		Type:      "change",
+		Body:      c,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/option"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/context"
+)
+
+// newTestReplayServer serves a websocket sending a conversation change with
+// counter 1 followed by a change with counter 3, and answers Event.replay
+// requests with the given success.  Replay requests are sent to replays.
+func newTestReplayServer(t *testing.T, success bool, replays chan<- *WebsocketRequest) *httptest.Server {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for _, counter := range []int{1, 3} {
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{
+				"type": "change",
+				"counter": %d,
+				"timestamp": "2017-01-01T00:00:0%dZ",
+				"body": {
+					"operation": "create",
+					"object": {"type": "Conversation", "id": "layer:///conversations/%d"},
+					"data": {"id": "layer:///conversations/%d"}
+				}
+			}`, counter, counter, counter, counter)))
+		}
+
+		for {
+			var p struct {
+				Body *WebsocketRequest `json:"body"`
+			}
+			if err := conn.ReadJSON(&p); err != nil {
+				return
+			}
+			replays <- p.Body
+
+			data := `{}`
+			if !success {
+				data = `{"id":"invalid_request_id","code":105,"message":"Replay is unavailable"}`
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{
+				"type": "response",
+				"counter": 4,
+				"body": {"request_id": "%s", "method": "Event.replay", "success": %t, "data": %s}
+			}`, p.Body.RequestID, success, data)))
+		}
+	}))
+}
+
+func newTestReplayClient(t *testing.T, s *httptest.Server, rest *httptest.Server) *Client {
+	wu, _ := url.Parse("ws" + strings.TrimPrefix(s.URL, "http"))
+	u, _ := url.Parse(s.URL)
+	if rest != nil {
+		u, _ = url.Parse(rest.URL)
+	}
+	c, err := NewClient(
+		context.Background(),
+		"24f43c32-4d95-11e4-b3a2-0fd00000020d",
+		option.WithSessionToken("token"),
+		option.OverrideURL(u),
+		option.WithWebsocketURL(wu),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestWebsocketReplay(t *testing.T) {
+	replays := make(chan *WebsocketRequest, 1)
+	s := newTestReplayServer(t, true, replays)
+	defer s.Close()
+
+	c := newTestReplayClient(t, s, nil)
+	gaps := make(chan *WebsocketGap, 1)
+	c.Websocket.OnGap(func(w *Websocket, gap *WebsocketGap) {
+		gaps <- gap
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Websocket.Listen(ctx)
+	defer c.Websocket.Close()
+
+	select {
+	case req := <-replays:
+		if req.Method != WebsocketMethodEventReplay {
+			t.Fatalf("Expected %s request, got %s", WebsocketMethodEventReplay, req.Method)
+		}
+		data, _ := json.Marshal(req.Data)
+		if string(data) != `{"from_timestamp":"2017-01-01T00:00:01Z"}` {
+			t.Fatalf("Expected replay from the last timestamp, got %s", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for replay request")
+	}
+
+	select {
+	case gap := <-gaps:
+		t.Fatalf("Expected the gap to be replayed, got %v", gap.Err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWebsocketResync(t *testing.T) {
+	rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/conversations":
+			fmt.Fprint(w, `[{"id":"layer:///conversations/3"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"id":"not_found","code":102,"message":"Conversation not found"}`)
+		}
+	}))
+	defer rest.Close()
+
+	replays := make(chan *WebsocketRequest, 1)
+	s := newTestReplayServer(t, false, replays)
+	defer s.Close()
+
+	c := newTestReplayClient(t, s, rest)
+	resynced := make(chan string, 10)
+	c.Websocket.HandleFunc(WebsocketChangeConversationResync, func(w *Websocket, p *WebsocketPacket) {
+		resynced <- p.Body.(*WebsocketChange).Object.ID
+	})
+	deleted := make(chan string, 10)
+	c.Websocket.HandleFunc(WebsocketChangeConversationDelete, func(w *Websocket, p *WebsocketPacket) {
+		deleted <- p.Body.(*WebsocketChange).Object.ID
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Websocket.Listen(ctx)
+	defer c.Websocket.Close()
+
+	select {
+	case id := <-resynced:
+		if id != "layer:///conversations/3" {
+			t.Fatalf("Expected conversation 3 to be resynced, got %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for resync")
+	}
+
+	// Conversation 1 was seen on the websocket but no longer exists
+	select {
+	case id := <-deleted:
+		if id != "layer:///conversations/1" {
+			t.Fatalf("Expected conversation 1 to be deleted, got %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for deleted conversation")
+	}
+}
+
+func TestWebsocketUnrecoverableGap(t *testing.T) {
+	rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"id":"access_denied","code":2,"message":"Access denied"}`)
+	}))
+	defer rest.Close()
+
+	replays := make(chan *WebsocketRequest, 1)
+	s := newTestReplayServer(t, false, replays)
+	defer s.Close()
+
+	c := newTestReplayClient(t, s, rest)
+	gaps := make(chan *WebsocketGap, 1)
+	c.Websocket.OnGap(func(w *Websocket, gap *WebsocketGap) {
+		gaps <- gap
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Websocket.Listen(ctx)
+	defer c.Websocket.Close()
+
+	select {
+	case gap := <-gaps:
+		if gap.LastCounter != 1 || gap.Counter != 3 {
+			t.Fatalf("Expected a gap between counters 1 and 3, got %d and %d", gap.LastCounter, gap.Counter)
+		}
+		if gap.Err == nil {
+			t.Fatal("Expected the resync error to be reported")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for gap")
+	}
+}
@@ -0,0 +1,243 @@
+package client
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+// Typing indicator states
+const (
+	TypingStarted  = "started"
+	TypingPaused   = "paused"
+	TypingFinished = "finished"
+)
+
+const (
+	// TypingThrottle is the minimum interval between repeated typing signals
+	// with the same state for a conversation
+	TypingThrottle = 2500 * time.Millisecond
+
+	// TypingStartedExpiry and TypingPausedExpiry are how long another
+	// participant's started and paused states last without a new signal
+	TypingStartedExpiry = 6 * time.Second
+	TypingPausedExpiry  = 30 * time.Second
+)
+
+// WebsocketTypingData is the data of a typing indicator signal
+type WebsocketTypingData struct {
+	Action string                `json:"action"`
+	Sender *common.BasicIdentity `json:"sender,omitempty"`
+}
+
+// TypingIndicator is the typing state of a participant in a conversation
+type TypingIndicator struct {
+	ConversationID string
+	Identity       *common.BasicIdentity
+	State          string
+}
+
+// websocketTyping tracks the typing signals we have sent, for throttling,
+// and the typing state of other participants
+type websocketTyping struct {
+	sync.Mutex
+	sent     map[string]typingSignalSent
+	received map[string]map[string]*typingState
+
+	// startedExpiry and pausedExpiry override the protocol timeouts in tests
+	startedExpiry time.Duration
+	pausedExpiry  time.Duration
+}
+
+type typingSignalSent struct {
+	state string
+	at    time.Time
+}
+
+type typingState struct {
+	indicator *TypingIndicator
+	expiry    *time.Timer
+}
+
+// throttle records a typing signal about to be sent, returning false if the
+// same state was sent for the conversation within TypingThrottle
+func (t *websocketTyping) throttle(conversationID, state string, now time.Time) bool {
+	t.Lock()
+	defer t.Unlock()
+	if t.sent == nil {
+		t.sent = make(map[string]typingSignalSent)
+	}
+	last, ok := t.sent[conversationID]
+	if ok && last.state == state && now.Sub(last.at) < TypingThrottle {
+		return false
+	}
+	if state == TypingFinished {
+		delete(t.sent, conversationID)
+	} else {
+		t.sent[conversationID] = typingSignalSent{state: state, at: now}
+	}
+	return true
+}
+
+// update records a participant's typing state, calling expire when the state
+// lapses.  It returns false if the state has not changed.
+func (t *websocketTyping) update(indicator *TypingIndicator, expire func(*TypingIndicator)) bool {
+	t.Lock()
+	defer t.Unlock()
+	if t.received == nil {
+		t.received = make(map[string]map[string]*typingState)
+	}
+	conversation, ok := t.received[indicator.ConversationID]
+	if !ok {
+		conversation = make(map[string]*typingState)
+		t.received[indicator.ConversationID] = conversation
+	}
+
+	id := indicator.Identity.ID
+	if id == "" {
+		id = indicator.Identity.UserID
+	}
+	current, ok := conversation[id]
+	if ok {
+		current.expiry.Stop()
+	}
+	changed := !ok || current.indicator.State != indicator.State
+
+	if indicator.State == TypingFinished {
+		delete(conversation, id)
+		if len(conversation) == 0 {
+			delete(t.received, indicator.ConversationID)
+		}
+		return ok
+	}
+
+	timeout := t.expiry(indicator.State)
+	state := &typingState{indicator: indicator}
+	state.expiry = time.AfterFunc(timeout, func() {
+		t.Lock()
+		if conversation[id] != state {
+			t.Unlock()
+			return
+		}
+		delete(conversation, id)
+		if len(conversation) == 0 && t.received[indicator.ConversationID] != nil {
+			delete(t.received, indicator.ConversationID)
+		}
+		t.Unlock()
+
+		expire(&TypingIndicator{
+			ConversationID: indicator.ConversationID,
+			Identity:       indicator.Identity,
+			State:          TypingFinished,
+		})
+	})
+	conversation[id] = state
+	return changed
+}
+
+// expiry returns how long a typing state lasts without a new signal
+func (t *websocketTyping) expiry(state string) time.Duration {
+	if state == TypingPaused {
+		if t.pausedExpiry > 0 {
+			return t.pausedExpiry
+		}
+		return TypingPausedExpiry
+	}
+	if t.startedExpiry > 0 {
+		return t.startedExpiry
+	}
+	return TypingStartedExpiry
+}
+
+// indicators returns the participants typing in a conversation
+func (t *websocketTyping) indicators(conversationID string) []*TypingIndicator {
+	t.Lock()
+	defer t.Unlock()
+	var indicators []*TypingIndicator
+	for _, state := range t.received[conversationID] {
+		indicators = append(indicators, state.indicator)
+	}
+	sort.Slice(indicators, func(i, j int) bool {
+		return indicators[i].Identity.ID < indicators[j].Identity.ID
+	})
+	return indicators
+}
+
+// StartTyping tells the other participants that the user is typing.  It can
+// be called on every keystroke, as repeated signals are throttled.
+func (convo *Conversation) StartTyping(ctx context.Context) error {
+	return convo.Client.Websocket.sendTyping(ctx, convo.ID, TypingStarted)
+}
+
+// PauseTyping tells the other participants that the user has stopped typing
+// but has not cleared their input
+func (convo *Conversation) PauseTyping(ctx context.Context) error {
+	return convo.Client.Websocket.sendTyping(ctx, convo.ID, TypingPaused)
+}
+
+// StopTyping tells the other participants that the user has finished typing
+func (convo *Conversation) StopTyping(ctx context.Context) error {
+	return convo.Client.Websocket.sendTyping(ctx, convo.ID, TypingFinished)
+}
+
+// Typing returns the other participants currently typing in the conversation
+func (convo *Conversation) Typing() []*TypingIndicator {
+	return convo.Client.Websocket.typing.indicators(convo.ID)
+}
+
+// sendTyping sends a typing indicator signal unless it is throttled
+func (w *Websocket) sendTyping(ctx context.Context, conversationID, state string) error {
+	if !w.typing.throttle(conversationID, state, time.Now()) {
+		return nil
+	}
+
+	return w.Send(ctx, &WebsocketPacket{
+		Type: "signal",
+		Body: &WebsocketSignal{
+			Type:   WebsocketSignalTyping,
+			Object: WebsocketSignalObject{ID: conversationID},
+			Data:   &WebsocketTypingData{Action: state},
+		},
+	})
+}
+
+// OnTyping registers a handler called when another participant's typing
+// state changes, including when it expires
+func (w *Websocket) OnTyping(h func(*Websocket, *TypingIndicator)) WebsocketEventHandlerRemover {
+	return w.HandleFunc(WebsocketEventTyping, func(w *Websocket, p *WebsocketPacket) {
+		if indicator, ok := p.Body.(*TypingIndicator); ok {
+			h(w, indicator)
+		}
+	})
+}
+
+// receiveTyping records a typing indicator signal, dispatching a typing
+// event if the participant's state has changed
+func (w *Websocket) receiveTyping(s *WebsocketSignal) {
+	data, ok := s.Data.(*WebsocketTypingData)
+	if !ok || data.Sender == nil {
+		return
+	}
+
+	indicator := &TypingIndicator{
+		ConversationID: s.Object.ID,
+		Identity:       data.Sender,
+		State:          data.Action,
+	}
+	if w.typing.update(indicator, w.dispatchTyping) {
+		w.dispatchTyping(indicator)
+	}
+}
+
+// dispatchTyping dispatches a typing event to registered handlers
+func (w *Websocket) dispatchTyping(indicator *TypingIndicator) {
+	w.dispatch(&WebsocketPacket{
+		Type:      "typing",
+		Body:      indicator,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/context"
+)
+
+func TestTypingThrottle(t *testing.T) {
+	typing := &websocketTyping{}
+	now := time.Now()
+
+	if !typing.throttle("c1", TypingStarted, now) {
+		t.Fatal("Expected the first signal to be sent")
+	}
+	if typing.throttle("c1", TypingStarted, now.Add(time.Second)) {
+		t.Fatal("Expected a repeated signal to be throttled")
+	}
+	if !typing.throttle("c2", TypingStarted, now.Add(time.Second)) {
+		t.Fatal("Expected signals for another conversation to be sent")
+	}
+	if !typing.throttle("c1", TypingStarted, now.Add(TypingThrottle)) {
+		t.Fatal("Expected a repeated signal to be sent after the throttle interval")
+	}
+	if !typing.throttle("c1", TypingFinished, now.Add(TypingThrottle)) {
+		t.Fatal("Expected a state change to be sent")
+	}
+	if !typing.throttle("c1", TypingStarted, now.Add(TypingThrottle)) {
+		t.Fatal("Expected typing to restart after finishing")
+	}
+}
+
+func TestTypingExpiry(t *testing.T) {
+	typing := &websocketTyping{startedExpiry: 20 * time.Millisecond}
+	expired := make(chan *TypingIndicator, 1)
+	sender := &common.BasicIdentity{ID: "layer:///identities/a"}
+
+	indicator := &TypingIndicator{ConversationID: "c1", Identity: sender, State: TypingStarted}
+	if !typing.update(indicator, func(i *TypingIndicator) { expired <- i }) {
+		t.Fatal("Expected a new typing state to be reported")
+	}
+	indicator = &TypingIndicator{ConversationID: "c1", Identity: sender, State: TypingStarted}
+	if typing.update(indicator, func(i *TypingIndicator) { expired <- i }) {
+		t.Fatal("Expected a repeated typing state not to be reported")
+	}
+	if n := len(typing.indicators("c1")); n != 1 {
+		t.Fatalf("Expected one participant typing, got %d", n)
+	}
+
+	select {
+	case i := <-expired:
+		if i.State != TypingFinished || i.Identity != sender {
+			t.Fatalf("Expected the participant to finish typing, got %s", i.State)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for typing state to expire")
+	}
+	if n := len(typing.indicators("c1")); n != 0 {
+		t.Fatalf("Expected nobody typing, got %d", n)
+	}
+}
+
+func TestTypingIndicators(t *testing.T) {
+	signals := make(chan *WebsocketSignal, 10)
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var data json.RawMessage
+			p := &WebsocketPacket{Body: &WebsocketSignal{Data: &data}}
+			if err := conn.ReadJSON(p); err != nil {
+				return
+			}
+			signal := p.Body.(*WebsocketSignal)
+			typing := &WebsocketTypingData{}
+			json.Unmarshal(data, typing)
+			signal.Data = typing
+			signals <- signal
+
+			// Echo the signal as another participant
+			typing.Sender = &common.BasicIdentity{ID: "layer:///identities/b", UserID: "b"}
+			conn.WriteJSON(p)
+		}
+	}))
+	defer s.Close()
+
+	c := newTestCallClient(t, s)
+	defer c.Websocket.Close()
+	indicators := make(chan *TypingIndicator, 10)
+	c.Websocket.OnTyping(func(w *Websocket, i *TypingIndicator) {
+		indicators <- i
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Websocket.Listen(ctx)
+
+	convo := &Conversation{Client: c}
+	convo.ID = "layer:///conversations/1"
+	for _, f := range []func(context.Context) error{convo.StartTyping, convo.StartTyping, convo.StopTyping} {
+		if err := f(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, state := range []string{TypingStarted, TypingFinished} {
+		select {
+		case signal := <-signals:
+			if signal.Type != WebsocketSignalTyping || signal.Object.ID != convo.ID {
+				t.Fatalf("Expected a typing signal for the conversation, got %s for %s", signal.Type, signal.Object.ID)
+			}
+			if action := signal.Data.(*WebsocketTypingData).Action; action != state {
+				t.Fatalf("Expected a %s signal, got %s", state, action)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for %s signal", state)
+		}
+
+		select {
+		case i := <-indicators:
+			if i.State != state || i.ConversationID != convo.ID || i.Identity.UserID != "b" {
+				t.Fatalf("Expected %s indicator from b, got %s from %s", state, i.State, i.Identity.UserID)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for %s indicator", state)
+		}
+	}
+
+	if n := len(convo.Typing()); n != 0 {
+		t.Fatalf("Expected nobody typing, got %d", n)
+	}
+}
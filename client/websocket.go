@@ -1,14 +1,16 @@
 package client
 
 import (
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/layerhq/go-client/common"
+
 	"github.com/buger/jsonparser"
 	"github.com/gorilla/websocket"
 	"golang.org/x/net/context"
@@ -20,8 +22,9 @@ const (
 	WebsocketMethodMessageCreate      = "Message.create"
 	WebsocketMethodPresenceUpdate     = "Presence.update"
 	WebsocketMethodPresenceSync       = "Presence.sync"
+	WebsocketMethodEventReplay        = "Event.replay"
 
-	WebsocketSignalTyping = "typing"
+	WebsocketSignalTyping = "typing_indicator"
 
 	WebsocketConversationCreate          = "Conversation.create"
 	WebsocketConversationDelete          = "Conversation.delete"
@@ -42,17 +45,51 @@ const (
 	WebsocketChangeConversationLastMessage     = "Change.Conversation.last_message"
 	WebsocketChangeMessageCreate               = "Change.Message.create"
 	WebsocketChangeMessageDelete               = "Change.Message.delete"
+
+	// WebsocketChangeConversationResync is dispatched with conversations
+	// fetched over the REST API after missed events could not be replayed
+	WebsocketChangeConversationResync = "Change.Conversation.resync"
 )
 
+// Connection state events, dispatched to handlers registered for the event
+// type with a *WebsocketConnectionEvent body.  Handlers registered for
+// "connected" still receive a *WebsocketResponse body when the websocket
+// connects.
+const (
+	WebsocketEventConnecting   = "connection.connecting"
+	WebsocketEventConnected    = "connection.connected"
+	WebsocketEventDisconnected = "connection.disconnected"
+	WebsocketEventReconnected  = "connection.reconnected"
+
+	// WebsocketEventGap is dispatched with a *WebsocketGap body when missed
+	// events cannot be recovered
+	WebsocketEventGap = "gap"
+
+	// WebsocketEventTyping is dispatched with a *TypingIndicator body when
+	// another participant's typing state changes
+	WebsocketEventTyping = "typing"
+
+	// WebsocketEventPresence is dispatched with an *IdentityPresence body
+	// when the presence of an identity changes
+	WebsocketEventPresence = "presence"
+)
+
+// ErrAlreadyReceiving is returned by Receive when another receiver is running
+var ErrAlreadyReceiving = errors.New("Websocket is already receiving")
+
 type Websocket struct {
 	client   *Client
-	dialer   *websocket.Dialer
 	conn     *websocket.Conn
 	handlers *websocketEventHandlerSet
 	sync.RWMutex
-	isListening bool
-	counter     int64
-	Headers     http.Header
+	receiver *websocketReceiver
+	reader   *websocketReader
+	isClosed bool
+	events   websocketEventQueue
+	recovery websocketRecovery
+	pending  websocketPendingSet
+	typing   websocketTyping
+	Headers  http.Header
 }
 
 type WebsocketPacket struct {
@@ -75,9 +112,10 @@ type WebsocketChangeObject struct {
 }
 
 type WebsocketChangeData struct {
-	Operation string `json:"operation"`
-	Property  string `json:"property"`
-	ID        string `json:"id"`
+	Operation string      `json:"operation"`
+	Property  string      `json:"property"`
+	ID        string      `json:"id"`
+	Value     interface{} `json:"value,omitempty"`
 }
 
 type WebsocketRequest struct {
@@ -91,10 +129,31 @@ type WebsocketResponse struct {
 	RequestID string      `json:"request_id"`
 	Method    string      `json:"method"`
 	ObjectID  string      `json:"object_id,omitempty"`
+	Success   bool        `json:"success"`
 	Data      interface{} `json:"data,omitempty"`
 }
 
+// WebsocketSignal is an ephemeral notification, such as a typing indicator
 type WebsocketSignal struct {
+	Type   string                `json:"type"`
+	Object WebsocketSignalObject `json:"object"`
+	Data   interface{}           `json:"data,omitempty"`
+}
+
+type WebsocketSignalObject struct {
+	ID string `json:"id"`
+}
+
+// WebsocketConnectionEvent describes a change in the websocket connection
+type WebsocketConnectionEvent struct {
+	// Type is one of the WebsocketEvent constants
+	Type string
+
+	// Attempt is the reconnection attempt, or zero for the first connection
+	Attempt int
+
+	// Err is the error that dropped the connection, if any
+	Err error
 }
 
 // An interface to handle websocket event callbacks
@@ -108,10 +167,68 @@ type WebsocketEventHandlerRemover interface {
 
 type WebsocketHandlerFunc func(*Websocket, *WebsocketPacket)
 
+// websocketEventHandlerRemovers removes a group of handlers at once
+type websocketEventHandlerRemovers []WebsocketEventHandlerRemover
+
+func (rs websocketEventHandlerRemovers) Remove() {
+	for _, r := range rs {
+		r.Remove()
+	}
+}
+
 func (hf WebsocketHandlerFunc) Handle(w *Websocket, p *WebsocketPacket) {
 	hf(w, p)
 }
 
+// websocketReceiver is the function packets are passed to by Receive
+type websocketReceiver struct {
+	ctx context.Context
+	f   func(context.Context, *WebsocketPacket)
+}
+
+// websocketReader is the loop reading packets from the websocket, which runs
+// until the websocket is closed or the connection cannot be recovered
+type websocketReader struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// websocketEventQueue calls queued functions in order on a separate
+// goroutine, so that event handlers do not block reading the websocket
+type websocketEventQueue struct {
+	sync.Mutex
+	queue   []func()
+	running bool
+}
+
+func (q *websocketEventQueue) push(f func()) {
+	q.Lock()
+	defer q.Unlock()
+	q.queue = append(q.queue, f)
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+}
+
+func (q *websocketEventQueue) run() {
+	for {
+		q.Lock()
+		if len(q.queue) == 0 {
+			q.running = false
+			q.Unlock()
+			return
+		}
+		f := q.queue[0]
+		q.queue[0] = nil
+		q.queue = q.queue[1:]
+		q.Unlock()
+
+		f()
+	}
+}
+
 type websocketEventHandlerSet struct {
 	set map[string][]*websocketEventHandlerNode
 	sync.RWMutex
@@ -127,10 +244,22 @@ func (hn *websocketEventHandlerNode) Handle(w *Websocket, p *WebsocketPacket) {
 	hn.handler.Handle(w, p)
 }
 
+// Remove unregisters the handler, leaving other handlers for the method
 func (hn *websocketEventHandlerNode) Remove() {
 	hn.set.Lock()
 	defer hn.set.Unlock()
-	delete(hn.set.set, hn.method)
+	nodes := hn.set.set[hn.method]
+	for i, node := range nodes {
+		if node == hn {
+			nodes = append(nodes[:i:i], nodes[i+1:]...)
+			break
+		}
+	}
+	if len(nodes) == 0 {
+		delete(hn.set.set, hn.method)
+	} else {
+		hn.set.set[hn.method] = nodes
+	}
 }
 
 func newHandlerSet() *websocketEventHandlerSet {
@@ -158,28 +287,18 @@ func (hs *websocketEventHandlerSet) add(method string, h WebsocketEventHandler)
 	return node
 }
 
-// Dispatch events to registered handlers
+// Dispatch events to registered handlers.  The handlers are called without
+// holding the lock so that they can send packets and register handlers.
 func (hs *websocketEventHandlerSet) dispatch(w *Websocket, p *WebsocketPacket) {
-	hs.Lock()
-	defer hs.Unlock()
-
-	handlerName := ""
-	switch p.Body.(type) {
-	case *WebsocketResponse:
-		r := p.Body.(*WebsocketResponse)
-		handlerName = strings.ToLower(r.Method)
-	case *WebsocketChange:
-		c := p.Body.(*WebsocketChange)
-		handlerName = strings.ToLower(fmt.Sprintf("Change.%s.%s", c.Object.Type, c.Operation))
-	default:
-		handlerName = "Unknown"
-	}
-
-	set, ok := hs.set[handlerName]
-	if !ok {
+	eventType := packetEventType(p)
+	hs.RLock()
+	set := append([]*websocketEventHandlerNode(nil), hs.set[strings.ToLower(eventType)]...)
+	hs.RUnlock()
+	if len(set) == 0 {
 		return
 	}
 
+	metrics := w.metrics()
 	wg := &sync.WaitGroup{}
 	for _, h := range set {
 		wg.Add(1)
@@ -187,16 +306,39 @@ func (hs *websocketEventHandlerSet) dispatch(w *Websocket, p *WebsocketPacket) {
 		// Create a copy of the pointer
 		hc := h
 		go func() {
+			start := time.Now()
 			hc.Handle(w, p)
+			if metrics != nil {
+				metrics.ObserveHandler(eventType, time.Since(start))
+			}
 			wg.Done()
 		}()
 	}
 	wg.Wait()
 }
 
-var wsHeaders = http.Header{
-	"Origin":                 {"http://local.host:80"},
-	"Sec-WebSocket-Protocol": {"layer-3.0"},
+// packetEventType returns the event type of a packet, such as
+// "Change.Message.create" for change events or the method for responses
+func packetEventType(p *WebsocketPacket) string {
+	switch p.Body.(type) {
+	case *WebsocketResponse:
+		r := p.Body.(*WebsocketResponse)
+		return r.Method
+	case *WebsocketChange:
+		c := p.Body.(*WebsocketChange)
+		return fmt.Sprintf("Change.%s.%s", c.Object.Type, c.Operation)
+	case *WebsocketConnectionEvent:
+		return p.Body.(*WebsocketConnectionEvent).Type
+	case *WebsocketGap:
+		return WebsocketEventGap
+	case *WebsocketSignal:
+		return p.Body.(*WebsocketSignal).Type
+	case *TypingIndicator:
+		return WebsocketEventTyping
+	case *IdentityPresence:
+		return WebsocketEventPresence
+	}
+	return "Unknown"
 }
 
 // NewWebsocket creates a new Websocket with options
@@ -211,95 +353,408 @@ func NewWebsocket(opts ...WebsocketOption) (ws *Websocket, err error) {
 	return
 }
 
-// Connect a websocket
+// Connect a websocket, doing nothing if it is already connected
 func (w *Websocket) Connect() error {
+	return w.connect(context.TODO(), 0)
+}
+
+// connect dials the websocket unless it is already connected, dispatching
+// connection state events
+func (w *Websocket) connect(ctx context.Context, attempt int) error {
+	w.Lock()
 	if w.conn != nil {
+		w.Unlock()
 		return nil
 	}
+	w.isClosed = false
+	w.Unlock()
+
+	w.dispatchConnectionEvent(&WebsocketConnectionEvent{Type: WebsocketEventConnecting, Attempt: attempt})
+	conn, err := w.dial(ctx)
+	if err != nil {
+		return err
+	}
 
 	w.Lock()
-	defer w.Unlock()
-	w.dialer = &websocket.Dialer{}
+	if w.conn != nil {
+		// Another caller connected the websocket first
+		w.Unlock()
+		conn.Close()
+		return nil
+	}
+	w.conn = conn
+	w.Unlock()
+
+	w.dispatchConnectionEvent(&WebsocketConnectionEvent{Type: WebsocketEventConnected, Attempt: attempt})
+
+	// Dispatch a connected event
+	w.dispatch(&WebsocketPacket{
+		Body:      &WebsocketResponse{Method: "connected"},
+		Timestamp: time.Now(),
+	})
+	return nil
+}
 
+// dial opens a websocket connection with the current session token.  If the
+// handshake is rejected the session is refreshed and the dial retried once.
+func (w *Websocket) dial(ctx context.Context) (*websocket.Conn, error) {
 	if w.client.transport.Session == nil {
-		return fmt.Errorf("Invalid session")
+		return nil, fmt.Errorf("Invalid session")
 	}
 
-	token, err := w.client.transport.Session.Token(context.TODO())
+	dialer := w.dialer()
+
+	token, err := w.client.transport.Session.Token(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	logger := w.client.logger()
+	conn, res, err := w.dialWithToken(dialer, token)
+	if err == nil || res == nil || (res.StatusCode != http.StatusUnauthorized && res.StatusCode != http.StatusForbidden) {
+		return conn, err
+	}
+
+	// The session has expired or been revoked
+	logger.Info("Websocket session rejected, refreshing the session")
+	if token, err = w.client.transport.RefreshSession(ctx, token); err != nil {
+		return nil, fmt.Errorf("Error refreshing websocket session: %v", err)
+	}
+	conn, _, err = w.dialWithToken(dialer, token)
+	return conn, err
+}
+
+// dialer returns a websocket dialer using the TLS configuration and proxy of
+// the client's base transport
+func (w *Websocket) dialer() *websocket.Dialer {
+	dialer := &websocket.Dialer{Proxy: http.ProxyFromEnvironment}
+	base, ok := w.client.transport.BaseTransport().(*http.Transport)
+	if !ok {
+		return dialer
 	}
 
+	dialer.Proxy = base.Proxy
+	if base.TLSClientConfig != nil {
+		// The websocket handshake is always made over HTTP/1.1
+		dialer.TLSClientConfig = base.TLSClientConfig.Clone()
+		dialer.TLSClientConfig.NextProtos = nil
+	}
+	return dialer
+}
+
+// dialWithToken opens a websocket connection authenticated with a session token
+func (w *Websocket) dialWithToken(dialer *websocket.Dialer, token string) (*websocket.Conn, *http.Response, error) {
 	u := fmt.Sprintf("%s?session_token=%s", w.client.websocketURL.String(), token)
-	ws, _, err := w.dialer.Dial(u, wsHeaders)
+	logger := w.client.logger()
+	logger.Debug("Connecting websocket", "url", u)
+	conn, res, err := dialer.Dial(u, w.client.websocketHeaders())
 	if err != nil {
-		return err
+		logger.Warn("Error connecting websocket", "url", u, "error", err)
 	}
-	w.conn = ws
+	return conn, res, err
+}
 
-	// Dispatch a connected event
-	if w.handlers != nil {
-		w.handlers.dispatch(w, &WebsocketPacket{
-			Body: &WebsocketResponse{Method: "connected"},
-		})
+// reconnect discards a broken connection and reconnects with backoff
+// according to the reconnect policy
+func (w *Websocket) reconnect(ctx context.Context, conn *websocket.Conn, cause error) error {
+	w.Lock()
+	if w.conn != nil && w.conn != conn {
+		// The connection has already been replaced
+		w.Unlock()
+		return nil
 	}
+	w.conn = nil
+	w.Unlock()
+	conn.Close()
+
+	// Responses to requests sent on the broken connection will never arrive
+	w.pending.fail(fmt.Errorf("%w: %v", ErrWebsocketDisconnected, cause))
+	w.dispatchConnectionEvent(&WebsocketConnectionEvent{Type: WebsocketEventDisconnected, Err: cause})
+
+	policy := w.reconnectPolicy()
+	logger := w.client.logger()
+	for attempt := 1; policy.MaxAttempts <= 0 || attempt <= policy.MaxAttempts; attempt++ {
+		backoff := policy.Backoff(attempt)
+		logger.Debug("Reconnecting websocket", "attempt", attempt, "backoff", backoff, "error", cause)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
 
-	return nil
+		if w.closed() {
+			return nil
+		}
+		if cause = w.connect(ctx, attempt); cause == nil {
+			if metrics := w.metrics(); metrics != nil {
+				metrics.IncWebsocketReconnect()
+			}
+			w.dispatchConnectionEvent(&WebsocketConnectionEvent{Type: WebsocketEventReconnected, Attempt: attempt})
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Error reconnecting websocket after %d attempts: %w", policy.MaxAttempts, cause)
+}
+
+// reconnectPolicy returns the policy set with option.WithWebsocketReconnect,
+// or the default policy
+func (w *Websocket) reconnectPolicy() *common.RetryPolicy {
+	if settings := w.client.transport.DialSettings(); settings != nil && settings.WebsocketReconnect != nil {
+		return settings.WebsocketReconnect
+	}
+	return common.DefaultWebsocketReconnectPolicy()
+}
+
+// Close closes the websocket connection, stopping Receive without
+// reconnecting.  A subsequent Connect opens a new connection.
+func (w *Websocket) Close() error {
+	w.Lock()
+	conn := w.conn
+	w.conn = nil
+	w.isClosed = true
+	if w.reader != nil {
+		w.reader.cancel()
+		w.reader = nil
+	}
+	w.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	err := conn.Close()
+	w.pending.fail(ErrWebsocketDisconnected)
+	w.dispatchConnectionEvent(&WebsocketConnectionEvent{Type: WebsocketEventDisconnected})
+	return err
+}
+
+// closed returns true if the websocket has been closed with Close
+func (w *Websocket) closed() bool {
+	w.RLock()
+	defer w.RUnlock()
+	return w.isClosed
+}
+
+// connection returns the current connection, connecting if needed
+func (w *Websocket) connection(ctx context.Context) (*websocket.Conn, error) {
+	if err := w.connect(ctx, 0); err != nil {
+		return nil, err
+	}
+
+	w.RLock()
+	defer w.RUnlock()
+	if w.conn == nil {
+		return nil, fmt.Errorf("Websocket is not connected")
+	}
+	return w.conn, nil
+}
+
+// dispatch queues a packet for the registered handlers.  Handlers are called
+// in the order packets are dispatched, without blocking the caller.
+func (w *Websocket) dispatch(p *WebsocketPacket) {
+	w.events.push(func() {
+		w.RLock()
+		handlers := w.handlers
+		w.RUnlock()
+		if handlers != nil {
+			handlers.dispatch(w, p)
+		}
+	})
+}
+
+// dispatchConnectionEvent dispatches a connection state event to registered
+// handlers
+func (w *Websocket) dispatchConnectionEvent(e *WebsocketConnectionEvent) {
+	w.dispatch(&WebsocketPacket{
+		Type:      "connection",
+		Body:      e,
+		Timestamp: time.Now(),
+	})
 }
 
 // Send writes a websocket packet
 func (w *Websocket) Send(ctx context.Context, p *WebsocketPacket) error {
-	if err := w.Connect(); err != nil {
+	conn, err := w.connection(ctx)
+	if err != nil {
 		return err
 	}
 
 	w.Lock()
-	err := w.conn.WriteJSON(p)
+	err = conn.WriteJSON(p)
 	w.Unlock()
 
 	return err
 }
 
-// Start listening for websocket events
+// Start listening for websocket events, doing nothing if the websocket is
+// already receiving
 func (w *Websocket) Listen(ctx context.Context) error {
-	if w.isListening {
-		return nil
-	}
-	w.isListening = true
-	return w.Receive(ctx, func(ctx context.Context, p *WebsocketPacket) {
+	err := w.Receive(ctx, func(ctx context.Context, p *WebsocketPacket) {
 		// Dispatch
-		if w.handlers != nil {
-			w.handlers.dispatch(w, p)
+		w.RLock()
+		handlers := w.handlers
+		w.RUnlock()
+		if handlers != nil {
+			handlers.dispatch(w, p)
 		}
 	})
+	if err == ErrAlreadyReceiving {
+		return nil
+	}
+	return err
+}
+
+// metrics returns the metrics collector configured for the client, if any
+func (w *Websocket) metrics() common.Metrics {
+	if w.client == nil || w.client.transport == nil {
+		return nil
+	}
+	return w.client.transport.DialSettings().Metrics
 }
 
 // Register a handler for the specified method
 func (w *Websocket) HandleFunc(method string, h WebsocketHandlerFunc) WebsocketEventHandlerRemover {
+	w.Lock()
 	if w.handlers == nil {
+		w.handlers = newHandlerSet()
+	}
+	handlers := w.handlers
+	w.Unlock()
+	return handlers.add(method, h)
+}
+
+// OnConnectionEvent registers a handler for all connection state events
+func (w *Websocket) OnConnectionEvent(h func(*Websocket, *WebsocketConnectionEvent)) WebsocketEventHandlerRemover {
+	f := func(w *Websocket, p *WebsocketPacket) {
+		if e, ok := p.Body.(*WebsocketConnectionEvent); ok {
+			h(w, e)
+		}
+	}
+
+	var removers websocketEventHandlerRemovers
+	for _, event := range []string{WebsocketEventConnecting, WebsocketEventConnected, WebsocketEventDisconnected, WebsocketEventReconnected} {
+		removers = append(removers, w.HandleFunc(event, f))
+	}
+	return removers
+}
+
+// Receive calls f with messages from the websocket, reconnecting if the
+// connection drops.  It blocks until the websocket is closed, the context is
+// done, reconnection fails or an invalid packet is received.  Only one
+// receiver can run at a time.  Packets are read in the background, so f does
+// not block responses to calls from being received.
+func (w *Websocket) Receive(ctx context.Context, f func(context.Context, *WebsocketPacket)) error {
+	w.Lock()
+	if w.receiver != nil {
+		w.Unlock()
+		return ErrAlreadyReceiving
+	}
+	receiver := &websocketReceiver{ctx: ctx, f: f}
+	w.receiver = receiver
+	w.Unlock()
+
+	defer func() {
 		w.Lock()
-		w.handlers = &websocketEventHandlerSet{
-			set: make(map[string][]*websocketEventHandlerNode),
+		if w.receiver == receiver {
+			w.receiver = nil
 		}
 		w.Unlock()
+	}()
+
+	reader := w.startReading()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-reader.done:
+		return reader.err
 	}
-	return w.handlers.add(method, h)
 }
 
-// Receive calls f with messages from the websocket, note this blocks until an error is encountered
-func (w *Websocket) Receive(ctx context.Context, f func(context.Context, *WebsocketPacket)) error {
-	if err := w.Connect(); err != nil {
-		return err
+// startReading starts reading packets from the websocket unless it is already
+// being read.  Reading stops when the websocket is closed.
+func (w *Websocket) startReading() *websocketReader {
+	w.Lock()
+	defer w.Unlock()
+	if w.reader != nil {
+		return w.reader
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := &websocketReader{cancel: cancel, done: make(chan struct{})}
+	w.reader = reader
+	go func() {
+		reader.err = w.read(ctx)
+		w.Lock()
+		if w.reader == reader {
+			w.reader = nil
+		}
+		w.Unlock()
+		cancel()
+		close(reader.done)
+	}()
+	return reader
+}
+
+// receive passes a packet to the running receiver, or dispatches it to the
+// registered handlers if there is none
+func (w *Websocket) receive(p *WebsocketPacket) {
+	w.RLock()
+	receiver := w.receiver
+	handlers := w.handlers
+	w.RUnlock()
+
+	if receiver != nil {
+		receiver.f(receiver.ctx, p)
+	} else if handlers != nil {
+		handlers.dispatch(w, p)
 	}
+}
 
+// read reads packets until the context is done, the websocket is closed,
+// reconnection fails or an invalid packet is received.  Responses are
+// delivered to pending calls before the packet is queued for the receiver.
+func (w *Websocket) read(ctx context.Context) error {
 	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		conn, err := w.connection(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if w.closed() || ctx.Err() != nil {
+				return nil
+			}
+			if err := w.reconnect(ctx, conn, err); err != nil {
+				if w.closed() || ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+
+			// Recover the events sent while disconnected
+			if gap := w.recovery.reconnected(); gap != nil {
+				go w.recover(ctx, gap)
+			}
+			continue
+		}
+
 		var body json.RawMessage
 		p := &WebsocketPacket{Body: &body}
-		if err := w.conn.ReadJSON(p); err != nil {
-			time.Sleep(1 * time.Second)
-
-			// Re-connect the websocket
-			w.Connect()
+		if err := json.Unmarshal(data, p); err != nil {
+			return fmt.Errorf("Error parsing websocket packet: %v", err)
+		}
+		p.Timestamp = time.Now()
+		if ts, err := jsonparser.GetString(data, "timestamp"); err == nil {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				p.Timestamp = t
+			}
 		}
 
 		switch strings.ToLower(p.Type) {
@@ -313,13 +768,14 @@ func (w *Websocket) Receive(ctx context.Context, f func(context.Context, *Websoc
 
 			rawMsg := *r.Data.(*json.RawMessage)
 
-			counter, err := jsonparser.GetInt(rawMsg, "counter")
-			if err == nil {
-				w.counter = counter
+			// Failed requests carry a Layer error as their data
+			if !r.Success {
+				r.Data = common.NewRequestError(0, rawMsg)
+				break
 			}
 
 			id, err := jsonparser.GetString(rawMsg, "id")
-			if err != nil && strings.HasPrefix(id, "layer://") {
+			if err == nil && strings.HasPrefix(id, "layer:///") {
 				objectType := strings.ToLower(id[9:])
 				switch {
 				case strings.HasPrefix(objectType, "conversations"):
@@ -328,7 +784,7 @@ func (w *Websocket) Receive(ctx context.Context, f func(context.Context, *Websoc
 						r.Data = conversation
 					}
 				case strings.HasPrefix(objectType, "messages"):
-					var message *Message
+					var message *common.Message
 					if err = json.Unmarshal(rawMsg, &message); err == nil {
 						r.Data = message
 					}
@@ -349,15 +805,42 @@ func (w *Websocket) Receive(ctx context.Context, f func(context.Context, *Websoc
 						c.Data = conversation
 					}
 				case "message":
-					var message *Message
+					var message *common.Message
 					if err = json.Unmarshal(objectJSON, &message); err == nil {
 						c.Data = message
 					}
+				case "identity":
+					var ops []*WebsocketChangeData
+					if err = json.Unmarshal(objectJSON, &ops); err == nil {
+						c.Data = ops
+						w.receivePresence(c)
+					}
+				}
+			}
+		case "signal":
+			var data json.RawMessage
+			signal := &WebsocketSignal{Data: &data}
+			if err := json.Unmarshal(body, signal); err != nil {
+				return err
+			}
+			p.Body = signal
+			if signal.Type == WebsocketSignalTyping {
+				typing := &WebsocketTypingData{}
+				if err := json.Unmarshal(data, typing); err == nil {
+					signal.Data = typing
+					w.receiveTyping(signal)
 				}
 			}
 		}
-		f(ctx, p)
+		if gap := w.recovery.observe(conn, p); gap != nil {
+			go w.recover(ctx, gap)
+		}
+		if r, ok := p.Body.(*WebsocketResponse); ok {
+			w.pending.deliver(r)
+		}
+		if metrics := w.metrics(); metrics != nil {
+			metrics.IncEvent(packetEventType(p))
+		}
+		w.events.push(func() { w.receive(p) })
 	}
-
-	return nil
 }
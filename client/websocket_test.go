@@ -1,14 +1,26 @@
 package client
 
 import (
+	"crypto/x509"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/layerhq/go-client/common"
+	"github.com/layerhq/go-client/option"
+
+	"github.com/gorilla/websocket"
 	"golang.org/x/net/context"
 )
 
 func TestWebsocketReceive(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -32,7 +44,7 @@ func TestWebsocketReceive(t *testing.T) {
 		})
 
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
 		}
 	}()
 
@@ -54,6 +66,8 @@ func TestWebsocketReceive(t *testing.T) {
 }
 
 func TestWebsocketEventHandler(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -100,6 +114,8 @@ func TestWebsocketEventHandler(t *testing.T) {
 }
 
 func TestWebsocketMultipleEventHandler(t *testing.T) {
+	skipWithoutCredentials(t)
+
 	c, err := createTestClient()
 	if err != nil {
 		t.Fatal(err)
@@ -148,20 +164,160 @@ func TestWebsocketMultipleEventHandler(t *testing.T) {
 	}
 }
 
+func TestWebsocketReconnect(t *testing.T) {
+	var mu sync.Mutex
+	connections := 0
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		connections++
+		n := connections
+		mu.Unlock()
+
+		// Drop the first connection, and keep later connections open
+		if n == 1 {
+			conn.Close()
+			return
+		}
+		conn.ReadMessage()
+		conn.Close()
+	}))
+	defer s.Close()
+
+	wu, _ := url.Parse("ws" + strings.TrimPrefix(s.URL, "http"))
+	c, err := NewClient(
+		context.Background(),
+		"24f43c32-4d95-11e4-b3a2-0fd00000020d",
+		option.WithSessionToken("token"),
+		option.WithWebsocketURL(wu),
+		option.WithWebsocketReconnect(&common.RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan string, 10)
+	c.Websocket.OnConnectionEvent(func(w *Websocket, e *WebsocketConnectionEvent) {
+		events <- e.Type
+	})
+	connected := make(chan interface{}, 2)
+	c.Websocket.HandleFunc("connected", func(w *Websocket, p *WebsocketPacket) {
+		// Handlers can register handlers while events are dispatched
+		w.HandleFunc("connected", func(w *Websocket, p *WebsocketPacket) {}).Remove()
+		connected <- p.Body
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Websocket.Listen(ctx)
+
+	expected := []string{
+		WebsocketEventConnecting,
+		WebsocketEventConnected,
+		WebsocketEventDisconnected,
+		WebsocketEventConnecting,
+		WebsocketEventConnected,
+		WebsocketEventReconnected,
+	}
+	for _, e := range expected {
+		select {
+		case event := <-events:
+			if event != e {
+				t.Fatalf("Expected %s event, got %s", e, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for %s event", e)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if r, ok := (<-connected).(*WebsocketResponse); !ok || r.Method != "connected" {
+			t.Fatalf("Expected a connected response, got %+v", r)
+		}
+	}
+
+	if err := c.Websocket.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebsocketDialerSettings(t *testing.T) {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.ReadMessage()
+		conn.Close()
+	}))
+	defer s.Close()
+
+	// The websocket is dialed through the base transport's proxy and with
+	// the client's TLS settings
+	proxied := make(chan string, 1)
+	base := &http.Transport{
+		Proxy: func(r *http.Request) (*url.URL, error) {
+			proxied <- r.URL.Host
+			return nil, nil
+		},
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	wu, _ := url.Parse("wss" + strings.TrimPrefix(s.URL, "https"))
+	c, err := NewClient(
+		context.Background(),
+		"24f43c32-4d95-11e4-b3a2-0fd00000020d",
+		option.WithSessionToken("token"),
+		option.WithWebsocketURL(wu),
+		option.WithBaseTransport(base),
+		option.WithRootCAs(pool),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Websocket.Close()
+
+	if err := c.Websocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case host := <-proxied:
+		if host != wu.Host {
+			t.Fatalf("Expected the proxy to be asked for %s, got %s", wu.Host, host)
+		}
+	default:
+		t.Fatal("Expected the websocket to be dialed through the base transport proxy")
+	}
+}
+
 func ExampleWebsocket() {
+	ctx := context.Background()
+	c, err := NewClient(ctx, "APP_ID", option.WithSessionToken("SESSION_TOKEN"))
+	if err != nil {
+		fmt.Println(fmt.Sprintf("Error creating client: %v", err))
+		return
+	}
+
 	// Register your desired handlers prior to connecting to make sure they
 	// receive all events.
-	c.Websocket.HandleFunc(Websocket.WebsocketMessageCreate, func(w *Websocket, p *WebsocketPacket) {
-		switch p.Body.(type) {
-		case *WebsocketResponse:
-			res := p.Body.(WebsocketResponse)
-			message := res.Data.(*common.Message)
+	c.Websocket.HandleFunc(WebsocketChangeMessageCreate, func(w *Websocket, p *WebsocketPacket) {
+		if change, ok := p.Body.(*WebsocketChange); ok {
+			message := change.Data.(*common.Message)
 			fmt.Println(fmt.Sprintf("%+v", message))
 		}
 	})
 
-	ctx := context.Background()
-
 	// Connect to the websocket
 	c.Websocket.Connect()
 
@@ -0,0 +1,19 @@
+package common
+
+import (
+	"golang.org/x/net/context"
+)
+
+// BearerTokenSource supplies Server API bearer tokens, such as API keys that
+// are rotated by a secrets manager
+type BearerTokenSource interface {
+	// BearerToken returns the current bearer token
+	BearerToken(ctx context.Context) (string, error)
+}
+
+// BearerTokenSourceFunc adapts a function to the BearerTokenSource interface
+type BearerTokenSourceFunc func(ctx context.Context) (string, error)
+
+func (f BearerTokenSourceFunc) BearerToken(ctx context.Context) (string, error) {
+	return f(ctx)
+}
@@ -0,0 +1,24 @@
+package common
+
+// ResponseCache configures a cache of GET responses that are revalidated
+// with If-None-Match and If-Modified-Since.  Only responses with an ETag or
+// Last-Modified header are cached.
+type ResponseCache struct {
+	// MaxEntries limits the number of cached responses (default 1000)
+	MaxEntries int
+
+	// MaxBytes limits the total size of cached bodies (default 10 MiB)
+	MaxBytes int64
+
+	// MaxEntryBytes is the largest body that is cached (default 1 MiB)
+	MaxEntryBytes int64
+}
+
+// DefaultResponseCache returns a response cache with the default limits
+func DefaultResponseCache() *ResponseCache {
+	return &ResponseCache{
+		MaxEntries:    1000,
+		MaxBytes:      10 << 20,
+		MaxEntryBytes: 1 << 20,
+	}
+}
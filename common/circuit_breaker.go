@@ -0,0 +1,96 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCircuitOpen matches errors returned while a circuit breaker is open
+var ErrCircuitOpen = errors.New("Circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed allows requests through and counts failures
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen fails requests immediately
+	CircuitOpen
+
+	// CircuitHalfOpen allows a limited number of probe requests through to
+	// test whether the endpoint has recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreaker configures a circuit breaker for each host and endpoint
+// family.  Network errors and server errors count as failures.
+type CircuitBreaker struct {
+	// FailureRatio is the ratio of failed requests in a window that opens
+	// the circuit (default 0.5)
+	FailureRatio float64
+
+	// MinRequests is the number of requests in a window before the failure
+	// ratio is considered (default 10)
+	MinRequests int
+
+	// Window is the period over which failures are counted (default 10
+	// seconds)
+	Window time.Duration
+
+	// OpenTimeout is how long the circuit stays open before probe requests
+	// are allowed (default 30 seconds)
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is the number of probe requests allowed while half-open,
+	// all of which must succeed to close the circuit (default 1)
+	HalfOpenProbes int
+
+	// OnStateChange is called when a circuit changes state.  It must not
+	// block.
+	OnStateChange func(host, family string, from, to CircuitState)
+}
+
+// DefaultCircuitBreaker returns a circuit breaker with the default settings
+func DefaultCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureRatio:   0.5,
+		MinRequests:    10,
+		Window:         10 * time.Second,
+		OpenTimeout:    30 * time.Second,
+		HalfOpenProbes: 1,
+	}
+}
+
+// CircuitOpenError is returned for requests rejected by an open circuit
+type CircuitOpenError struct {
+	// Host and Family identify the circuit
+	Host   string
+	Family string
+
+	// RetryAfter is the time until probe requests are allowed, or zero if
+	// the circuit is half-open and waiting on probes
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("Circuit breaker is open for %s requests to %s", e.Family, e.Host)
+}
+
+// Is matches ErrCircuitOpen
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
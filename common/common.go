@@ -21,6 +21,7 @@ func ValidateUUID(id string) error {
 const (
 	IdentitiesName    = "identities"
 	ConversationsName = "conversations"
+	MessagesName      = "messages"
 )
 
 // LayerID creates a full Layer URL from a type and UUID
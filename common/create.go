@@ -0,0 +1,90 @@
+package common
+
+import (
+	"github.com/satori/go.uuid"
+)
+
+// CreateOption customizes a request that creates a conversation or message
+type CreateOption func(*CreateSettings)
+
+// CreateSettings holds the settings for a create request
+type CreateSettings struct {
+	// ID is the UUID of the object to create
+	ID string
+}
+
+// idempotencyKeyNamespace is the UUID namespace that IDs are derived from
+// idempotency keys in
+var idempotencyKeyNamespace = uuid.Must(uuid.FromString("1dd9d54c-4beb-4eee-ba86-d218613ac101"))
+
+// WithID returns a CreateOption that sets the UUID (or Layer URL) of the
+// created object.  Retrying a create with the same ID returns the existing
+// object rather than creating a duplicate.
+func WithID(id string) CreateOption {
+	return func(s *CreateSettings) {
+		s.ID = id
+	}
+}
+
+// WithIdempotencyKey returns a CreateOption that derives the ID of the created
+// object from a key identifying the operation, such as an inbound event ID.
+// Retrying a create with the same key returns the existing object rather
+// than creating a duplicate.
+func WithIdempotencyKey(key string) CreateOption {
+	return func(s *CreateSettings) {
+		s.ID = uuid.NewV5(idempotencyKeyNamespace, key).String()
+	}
+}
+
+// NewCreateSettings applies create options.  If neither WithID nor
+// WithIdempotencyKey is given a random ID is generated, which only makes the
+// retries within a single call idempotent: callers retrying a create
+// themselves must pass the same ID or key on every attempt.
+func NewCreateSettings(opts ...CreateOption) *CreateSettings {
+	var s CreateSettings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	if s.ID == "" {
+		s.ID = uuid.Must(uuid.NewV4()).String()
+	}
+	s.ID = UUIDFromLayerURL(s.ID)
+	return &s
+}
+
+// CreatedWith returns true if the conversation has the given ID and distinct
+// setting and includes the given participants, meaning it was created by an
+// earlier request with the same content
+func (c *Conversation) CreatedWith(id string, participants []string, distinct bool) bool {
+	if c == nil || UUIDFromLayerURL(c.ID) != UUIDFromLayerURL(id) || c.Distinct != distinct {
+		return false
+	}
+
+	existing := make(map[string]bool, len(c.Participants))
+	for _, p := range c.Participants {
+		if p != nil {
+			existing[p.UserID] = true
+			existing[p.ID] = true
+		}
+	}
+	for _, p := range participants {
+		if !existing[p] && !existing[LayerURL(IdentitiesName, p)] {
+			return false
+		}
+	}
+	return true
+}
+
+// CreatedWith returns true if the message has the given ID and parts,
+// meaning it was created by an earlier request with the same content
+func (m *Message) CreatedWith(id string, parts []*MessagePart) bool {
+	if m == nil || UUIDFromLayerURL(m.ID) != UUIDFromLayerURL(id) || len(m.Parts) != len(parts) {
+		return false
+	}
+	for i, p := range parts {
+		if m.Parts[i] == nil || p == nil || m.Parts[i].Body != p.Body || m.Parts[i].MimeType != p.MimeType {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestNewCreateSettings(t *testing.T) {
+	a, b := NewCreateSettings(), NewCreateSettings()
+	if ValidateUUID(a.ID) != nil || a.ID == b.ID {
+		t.Fatalf("Expected unique generated IDs, got %s and %s", a.ID, b.ID)
+	}
+
+	s := NewCreateSettings(WithID("layer:///messages/11111111-2222-4333-8444-555555555555"))
+	if s.ID != "11111111-2222-4333-8444-555555555555" {
+		t.Fatalf("Expected the UUID from the Layer URL, got %s", s.ID)
+	}
+
+	a, b = NewCreateSettings(WithIdempotencyKey("event-1")), NewCreateSettings(WithIdempotencyKey("event-1"))
+	if ValidateUUID(a.ID) != nil || a.ID != b.ID {
+		t.Fatalf("Expected the same ID for the same key, got %s and %s", a.ID, b.ID)
+	}
+	if s := NewCreateSettings(WithIdempotencyKey("event-2")); s.ID == a.ID {
+		t.Fatalf("Expected different IDs for different keys, got %s", s.ID)
+	}
+}
+
+func TestMessageCreatedWith(t *testing.T) {
+	id := "11111111-2222-4333-8444-555555555555"
+	parts := []*MessagePart{{Body: "hello", MimeType: "text/plain"}}
+	m := &Message{
+		ID:    LayerURL(MessagesName, id),
+		Parts: []*MessagePart{{ID: "part", Body: "hello", MimeType: "text/plain"}},
+	}
+
+	if !m.CreatedWith(id, parts) {
+		t.Fatal("Expected the message to match its create request")
+	}
+	if m.CreatedWith(id, []*MessagePart{{Body: "goodbye", MimeType: "text/plain"}}) {
+		t.Fatal("Expected different content not to match")
+	}
+	if m.CreatedWith("66666666-2222-4333-8444-555555555555", parts) {
+		t.Fatal("Expected a different ID not to match")
+	}
+}
@@ -1,5 +1,10 @@
 package common
 
+import (
+	"fmt"
+	"time"
+)
+
 type ClientCredentials struct {
 	// ApplicationID is the UUID of the application
 	ApplicationID string `json:"app_id"`
@@ -13,9 +18,24 @@ type ClientCredentials struct {
 	// Key is the encoded key data
 	Key *Key `json:"-"`
 
+	// Keys is a key ring used in place of Key when provider keys are rotated
+	Keys KeyRing `json:"-"`
+
 	// User is the username or identifier
 	User string `json:"-"`
 
 	// Token is the identity token
 	Token string `json:"identity_token"`
 }
+
+// SigningKey returns the key used to sign identity tokens at the given time,
+// preferring the key ring over the single key
+func (c *ClientCredentials) SigningKey(t time.Time) (*Key, error) {
+	if len(c.Keys) > 0 {
+		return c.Keys.Active(t)
+	}
+	if c.Key == nil {
+		return nil, fmt.Errorf("No key data specified")
+	}
+	return c.Key, nil
+}
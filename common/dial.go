@@ -1,17 +1,85 @@
 package common
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
 	"net/url"
 )
 
+// Middleware wraps a RoundTripper to intercept requests and responses
+type Middleware func(http.RoundTripper) http.RoundTripper
+
 type DialSettings struct {
-	BaseURL           *url.URL
-	UserAgent         string
-	BearerToken       string
-	SessionToken      string
-	Headers           map[string][]string
-	ClientCredentials *ClientCredentials
-	Key               *Key
-	TokenFunc         func(user, nonce string) (token string, err error)
-	AllowInsecure     bool
+	BaseURL              *url.URL
+	WebsocketURL         *url.URL
+	APIVersion           string
+	WebsocketOrigin      string
+	WebsocketSubprotocol string
+	WebsocketReconnect   *RetryPolicy
+	UserAgent            string
+	BearerToken          string
+	BearerTokenSource    BearerTokenSource
+	SessionToken         string
+	Headers              map[string][]string
+	ClientCredentials    *ClientCredentials
+	Key                  *Key
+	TokenFunc            func(user, nonce string) (token string, err error)
+	RemoteTokenProvider  *RemoteTokenProvider
+	TokenStore           TokenStore
+	CircuitBreaker       *CircuitBreaker
+	ResponseCache        *ResponseCache
+	RetryPolicy          *RetryPolicy
+	RateLimit            *RateLimit
+	EndpointRateLimits   map[string]*RateLimit
+	AllowInsecure        bool
+	HTTPClient           *http.Client
+	BaseTransport        http.RoundTripper
+	RootCAs              *x509.CertPool
+	ClientCertificates   []tls.Certificate
+	Middleware           []Middleware
+	Tracer               Tracer
+	Metrics              Metrics
+	Logger               Logger
+	Certificate          *Certificate
+	KeyRing              KeyRing
+
+	// Err is the first error encountered applying options, such as a
+	// certificate file that cannot be read
+	Err error
+}
+
+// ApplicationID returns the given application ID, or the application ID from
+// the certificate if none is given
+func (s *DialSettings) ApplicationID(appID string) string {
+	if appID == "" && s.Certificate != nil {
+		return UUIDFromLayerURL(s.Certificate.ApplicationID)
+	}
+	return appID
+}
+
+// CustomTLS returns true if the settings require a non-default TLS
+// configuration
+func (s *DialSettings) CustomTLS() bool {
+	return s.AllowInsecure || s.RootCAs != nil || len(s.ClientCertificates) > 0
+}
+
+// TLSConfig returns a copy of the base TLS configuration (which may be nil)
+// with the TLS settings applied
+func (s *DialSettings) TLSConfig(base *tls.Config) *tls.Config {
+	c := &tls.Config{}
+	if base != nil {
+		c = base.Clone()
+	}
+
+	if s.AllowInsecure {
+		c.InsecureSkipVerify = true
+	}
+	if s.RootCAs != nil {
+		c.RootCAs = s.RootCAs
+	}
+	if len(s.ClientCertificates) > 0 {
+		c.Certificates = append(c.Certificates, s.ClientCertificates...)
+	}
+	return c
 }
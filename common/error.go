@@ -1,9 +1,25 @@
 package common
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 )
 
+// Errors that a RequestError matches with errors.Is
+var (
+	ErrNotFound           = errors.New("Not found")
+	ErrConflict           = errors.New("Conflict")
+	ErrForbidden          = errors.New("Forbidden")
+	ErrRateLimited        = errors.New("Rate limited")
+	ErrParticipantBlocked = errors.New("Participant blocked")
+	ErrAuthentication     = errors.New("Authentication required")
+)
+
+// RequestError is an error returned by the Layer API.  On a conflict, Data
+// holds the conflicting object, such as the existing distinct conversation.
 type RequestError struct {
 	StatusCode int         `json:"-"`
 	Code       int         `json:"code"`
@@ -14,5 +30,75 @@ type RequestError struct {
 }
 
 func (e RequestError) Error() string {
-	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+	if e.Message != "" {
+		return fmt.Sprintf("%d: %s", e.Code, e.Message)
+	}
+	// Websocket errors have no status code, so describe them by their ID
+	if e.StatusCode == 0 && e.ID != "" {
+		if e.Code != 0 {
+			return fmt.Sprintf("%d: %s", e.Code, e.ID)
+		}
+		return e.ID
+	}
+	return fmt.Sprintf("Status code is %d", e.StatusCode)
+}
+
+// Is reports whether the error matches one of the sentinel errors, based on
+// the status code or, for websocket errors, the error ID.  Both a conflicting
+// distinct conversation and a client-supplied ID already in use match
+// ErrConflict.
+func (e RequestError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.ID == "not_found"
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.ID == "conflict" || e.ID == "id_in_use"
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden || e.ID == "access_denied"
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || e.ID == "rate_limit_exceeded"
+	case ErrParticipantBlocked:
+		return e.ID == "participant_blocked"
+	case ErrAuthentication:
+		return e.StatusCode == http.StatusUnauthorized || e.ID == "authentication_required"
+	}
+	return false
+}
+
+// DecodeData decodes the error data into v
+func (e RequestError) DecodeData(v interface{}) error {
+	if e.Data == nil {
+		return fmt.Errorf("Error has no data")
+	}
+	b, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// NewRequestError builds a RequestError from a Layer error response body.
+// Bodies that are not Layer errors still produce a RequestError carrying the
+// status code.
+func NewRequestError(statusCode int, body []byte) RequestError {
+	var e RequestError
+	if err := json.Unmarshal(body, &e); err != nil {
+		e = RequestError{}
+	}
+	e.StatusCode = statusCode
+	return e
+}
+
+// ResponseError reads an unsuccessful response and returns it as a
+// RequestError.  The response body is consumed but not closed.
+func ResponseError(res *http.Response) RequestError {
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return RequestError{StatusCode: res.StatusCode}
+	}
+	e := NewRequestError(res.StatusCode, body)
+	if e.URL == "" && res.Request != nil && res.Request.URL != nil {
+		e.URL = res.Request.URL.Path
+	}
+	return e
 }
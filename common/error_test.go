@@ -0,0 +1,102 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRequestErrorIs(t *testing.T) {
+	tests := []struct {
+		err    RequestError
+		target error
+	}{
+		{RequestError{StatusCode: http.StatusNotFound}, ErrNotFound},
+		{RequestError{StatusCode: http.StatusConflict}, ErrConflict},
+		{RequestError{StatusCode: http.StatusForbidden}, ErrForbidden},
+		{RequestError{StatusCode: http.StatusTooManyRequests}, ErrRateLimited},
+		{RequestError{ID: "participant_blocked"}, ErrParticipantBlocked},
+		{RequestError{StatusCode: http.StatusUnauthorized}, ErrAuthentication},
+		{RequestError{ID: "not_found"}, ErrNotFound},
+		{RequestError{ID: "conflict"}, ErrConflict},
+		{RequestError{ID: "id_in_use"}, ErrConflict},
+		{RequestError{ID: "authentication_required"}, ErrAuthentication},
+	}
+
+	for _, test := range tests {
+		err := fmt.Errorf("Error sending request: %w", test.err)
+		if !errors.Is(err, test.target) {
+			t.Errorf("Expected %+v to match %v", test.err, test.target)
+		}
+		if errors.Is(err, errors.New("other")) {
+			t.Errorf("Expected %+v not to match an unrelated error", test.err)
+		}
+	}
+
+	if errors.Is(RequestError{StatusCode: http.StatusNotFound}, ErrConflict) {
+		t.Error("Expected a not found error not to match ErrConflict")
+	}
+	if errors.Is(RequestError{StatusCode: http.StatusUnprocessableEntity}, ErrParticipantBlocked) {
+		t.Error("Expected an unprocessable request not to match ErrParticipantBlocked")
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	body := `{"id":"conflict","code":111,"message":"The requested Conversation already exists","data":{"id":"layer:///conversations/1"}}`
+	res := &http.Response{
+		StatusCode: http.StatusConflict,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		Request:    &http.Request{URL: &url.URL{Path: "/conversations"}},
+	}
+
+	var err error = ResponseError(res)
+	var reqErr RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("Expected a RequestError, got %T", err)
+	}
+	if reqErr.StatusCode != http.StatusConflict || reqErr.Code != 111 || reqErr.URL != "/conversations" {
+		t.Fatalf("Unexpected error %+v", reqErr)
+	}
+
+	var conversation Conversation
+	if err := reqErr.DecodeData(&conversation); err != nil {
+		t.Fatal(err)
+	}
+	if conversation.ID != "layer:///conversations/1" {
+		t.Fatalf("Expected the conflicting conversation, got %+v", conversation)
+	}
+}
+
+func TestResponseErrorWithoutBody(t *testing.T) {
+	res := &http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Body:       ioutil.NopCloser(bytes.NewBufferString("<html></html>")),
+	}
+
+	err := ResponseError(res)
+	if err.StatusCode != http.StatusInternalServerError || err.Error() != "Status code is 500" {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+}
+
+func TestRequestErrorWithoutMessage(t *testing.T) {
+	tests := []struct {
+		err      RequestError
+		expected string
+	}{
+		{RequestError{ID: "not_found", Code: 102}, "102: not_found"},
+		{RequestError{ID: "not_found"}, "not_found"},
+		{RequestError{StatusCode: http.StatusNotFound, ID: "not_found"}, "Status code is 404"},
+		{RequestError{ID: "not_found", Code: 102, Message: "Not found"}, "102: Not found"},
+	}
+
+	for _, test := range tests {
+		if msg := test.err.Error(); msg != test.expected {
+			t.Errorf("Expected %q for %+v, got %q", test.expected, test.err, msg)
+		}
+	}
+}
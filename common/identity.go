@@ -57,4 +57,7 @@ type Identity struct {
 
 	// Metadata allows setting of arbitrary data on the identity
 	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Presence is the online status of the user, if known
+	Presence *Presence `json:"presence,omitempty"`
 }
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"time"
+)
+
+// IdentityTokenRequest is the body of a request to an identity provider for
+// a Layer identity token
+type IdentityTokenRequest struct {
+	// UserID is the user the token is requested for
+	UserID string `json:"user_id,omitempty"`
+
+	// Nonce is the nonce issued by Layer
+	Nonce string `json:"nonce"`
+}
+
+// IdentityTokenResponse is the body of an identity provider response
+type IdentityTokenResponse struct {
+	// IdentityToken is the signed Layer identity token
+	IdentityToken string `json:"identity_token"`
+}
+
+// RemoteTokenProvider configures a remote identity provider that mints
+// identity tokens, such as a handler from the provider package
+type RemoteTokenProvider struct {
+	// URL is the identity token endpoint
+	URL string
+
+	// Headers are sent with every request, for example to authenticate the
+	// caller with the identity provider
+	Headers map[string][]string
+
+	// Timeout limits each attempt (default 3 seconds)
+	Timeout time.Duration
+
+	// RetryPolicy controls retries of failed requests (default
+	// DefaultRetryPolicy limited to 3 attempts)
+	RetryPolicy *RetryPolicy
+}
@@ -1,8 +1,16 @@
 package common
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"time"
 )
 
 // Key contains key data
@@ -12,6 +20,23 @@ type Key struct {
 
 	// KeyPair contains the key data
 	KeyPair *KeyPair `json:"key_pair"`
+
+	// NotBefore is the time the key becomes active (not required)
+	NotBefore time.Time `json:"not_before,omitempty"`
+
+	// NotAfter is the time the key expires (not required)
+	NotAfter time.Time `json:"not_after,omitempty"`
+}
+
+// Active returns true if the key is valid for signing at the given time
+func (k *Key) Active(t time.Time) bool {
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !t.Before(k.NotAfter) {
+		return false
+	}
+	return true
 }
 
 // KeyPair contains a public and private key pair in PEM format strings
@@ -23,6 +48,41 @@ type KeyPair struct {
 	Private string `json:"private"`
 }
 
+// KeyRing holds several keys with overlapping validity windows, allowing
+// provider keys to be rotated without downtime
+type KeyRing []*Key
+
+// Active returns the key to sign with at the given time.  When several keys
+// are active, the most recently activated key is used.
+func (r KeyRing) Active(t time.Time) (*Key, error) {
+	var active *Key
+	for _, key := range r {
+		if key == nil || !key.Active(t) {
+			continue
+		}
+		if active == nil || key.NotBefore.After(active.NotBefore) {
+			active = key
+		}
+	}
+	if active == nil {
+		return nil, fmt.Errorf("No active key in key ring")
+	}
+	return active, nil
+}
+
+// Validate checks every key in the ring with ValidateKey
+func (r KeyRing) Validate() error {
+	if len(r) == 0 {
+		return fmt.Errorf("Key ring is empty")
+	}
+	for i, key := range r {
+		if err := ValidateKey(key); err != nil {
+			return fmt.Errorf("Invalid key %d in key ring: %v", i, err)
+		}
+	}
+	return nil
+}
+
 // Certificate contains Layer application credential data
 type Certificate struct {
 	*Key
@@ -43,27 +103,158 @@ type Certificate struct {
 	APIKey string `json:"api_key"`
 }
 
+// ParseCertificate parses and validates a Layer certificate in JSON format
+func ParseCertificate(data []byte) (*Certificate, error) {
+	var c *Certificate
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("Error parsing certificate JSON: %v", err)
+	}
+	if c == nil {
+		return nil, fmt.Errorf("Certificate is empty")
+	}
+	if err := ValidateCertificate(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ValidateCertificate confirms a certificate has an application ID and either
+// a valid key pair or an API key
+func ValidateCertificate(c *Certificate) error {
+	if c.ApplicationID == "" {
+		return fmt.Errorf("Invalid certificate: application_id is missing")
+	}
+	if err := ValidateUUID(UUIDFromLayerURL(c.ApplicationID)); err != nil {
+		return fmt.Errorf("Invalid certificate: application_id %q is not a valid UUID", c.ApplicationID)
+	}
+	if c.AccountID != "" {
+		if err := ValidateUUID(UUIDFromLayerURL(c.AccountID)); err != nil {
+			return fmt.Errorf("Invalid certificate: account_id %q is not a valid UUID", c.AccountID)
+		}
+	}
+
+	if c.Key == nil || c.KeyPair == nil {
+		if c.APIKey == "" {
+			return fmt.Errorf("Invalid certificate: either key_pair or api_key must be set")
+		}
+		return nil
+	}
+
+	if c.ProviderID == "" {
+		return fmt.Errorf("Invalid certificate: provider_id is missing")
+	}
+	if err := ValidateUUID(UUIDFromLayerURL(c.ProviderID)); err != nil {
+		return fmt.Errorf("Invalid certificate: provider_id %q is not a valid UUID", c.ProviderID)
+	}
+	if err := ValidateKey(c.Key); err != nil {
+		return fmt.Errorf("Invalid certificate: %v", err)
+	}
+	return nil
+}
+
 func ValidateKey(key *Key) error {
+	if key == nil {
+		return fmt.Errorf("No key specified")
+	}
 	if err := ValidateUUID(key.ID); err != nil {
-		return fmt.Errorf("Invalid key ID")
+		return fmt.Errorf("Invalid key ID %q", key.ID)
 	}
 
 	// Confirm private key
+	if key.KeyPair == nil {
+		return fmt.Errorf("No key pair specified")
+	}
 	if key.KeyPair.Private == "" {
 		return fmt.Errorf("No private key data specified")
 	}
-	p, _ := pem.Decode([]byte(key.KeyPair.Private))
-	if p == nil {
-		return fmt.Errorf("Invalid PEM data in private key")
+	private, err := ParsePrivateKey(key.KeyPair.Private)
+	if err != nil {
+		return err
 	}
 
 	// Confirm public key if present (not required)
 	if key.KeyPair.Public != "" {
-		p, _ := pem.Decode([]byte(key.KeyPair.Public))
-		if p == nil {
-			return fmt.Errorf("Invalid PEM data in public key")
+		public, err := ParsePublicKey(key.KeyPair.Public)
+		if err != nil {
+			return err
 		}
+
+		// The public key must belong to the private key
+		if !public.(interface{ Equal(crypto.PublicKey) bool }).Equal(private.Public()) {
+			return fmt.Errorf("Public key does not match private key")
+		}
+	}
+
+	if !key.NotBefore.IsZero() && !key.NotAfter.IsZero() && !key.NotBefore.Before(key.NotAfter) {
+		return fmt.Errorf("Key expires before it becomes active")
 	}
 
 	return nil
 }
+
+// ParsePrivateKey parses an RSA, ECDSA (P-256) or Ed25519 private key in PEM
+// format
+func ParsePrivateKey(data string) (crypto.Signer, error) {
+	p, _ := pem.Decode([]byte(data))
+	if p == nil {
+		return nil, fmt.Errorf("Invalid PEM data in private key")
+	}
+
+	var key interface{}
+	var err error
+	switch p.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(p.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(p.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(p.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing private key: %v", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("Unsupported elliptic curve %s", k.Curve.Params().Name)
+		}
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("Unsupported private key type %T", key)
+}
+
+// ParsePublicKey parses an RSA, ECDSA or Ed25519 public key in PEM format
+func ParsePublicKey(data string) (crypto.PublicKey, error) {
+	p, _ := pem.Decode([]byte(data))
+	if p == nil {
+		return nil, fmt.Errorf("Invalid PEM data in public key")
+	}
+
+	var key interface{}
+	var err error
+	switch p.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(p.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(p.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		key, err = x509.ParsePKIXPublicKey(p.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing public key: %v", err)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("Unsupported public key type %T", key)
+}
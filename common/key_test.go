@@ -0,0 +1,147 @@
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKeyID = "7f3e0b4a-8a0e-11e7-bb31-be2e44b06b34"
+
+func testKeyPair(t *testing.T, private crypto.Signer) *KeyPair {
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &KeyPair{
+		Private: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		Public:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, private := range []crypto.Signer{rsaKey, ecKey, edKey} {
+		key := &Key{ID: testKeyID, KeyPair: testKeyPair(t, private)}
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("Expected %T key to be valid, got %v", private, err)
+		}
+	}
+
+	// Public key from a different key pair
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key := &Key{ID: testKeyID, KeyPair: testKeyPair(t, ecKey)}
+	key.KeyPair.Public = testKeyPair(t, otherKey).Public
+	if err := ValidateKey(key); err == nil {
+		t.Error("Expected mismatched key pair to be rejected")
+	}
+
+	// Unsupported curve
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	key = &Key{ID: testKeyID, KeyPair: testKeyPair(t, p384Key)}
+	if err := ValidateKey(key); err == nil {
+		t.Error("Expected P-384 key to be rejected")
+	}
+
+	// Missing key data
+	if err := ValidateKey(nil); err == nil {
+		t.Error("Expected a nil key to be rejected")
+	}
+	if err := ValidateKey(&Key{ID: testKeyID}); err == nil {
+		t.Error("Expected a key without a key pair to be rejected")
+	}
+}
+
+func TestKeyRingValidate(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	valid := &Key{ID: testKeyID, KeyPair: testKeyPair(t, ecKey)}
+	if err := (KeyRing{valid}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ring := range []KeyRing{nil, {valid, nil}, {valid, {ID: testKeyID}}} {
+		if err := ring.Validate(); err == nil {
+			t.Errorf("Expected key ring %v to be rejected", ring)
+		}
+	}
+}
+
+func TestKeyRingActive(t *testing.T) {
+	now := time.Now()
+	expired := &Key{ID: "expired", NotAfter: now.Add(-time.Hour)}
+	current := &Key{ID: "current", NotBefore: now.Add(-30 * 24 * time.Hour)}
+	rotated := &Key{ID: "rotated", NotBefore: now.Add(-time.Hour), NotAfter: now.Add(90 * 24 * time.Hour)}
+	pending := &Key{ID: "pending", NotBefore: now.Add(time.Hour)}
+
+	ring := KeyRing{expired, current, rotated, pending}
+	key, err := ring.Active(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != rotated {
+		t.Fatalf("Expected the most recently activated key, got %s", key.ID)
+	}
+
+	key, err = ring.Active(now.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != pending {
+		t.Fatalf("Expected the pending key once active, got %s", key.ID)
+	}
+
+	if _, err := (KeyRing{expired}).Active(now); err == nil {
+		t.Fatal("Expected an error when no key is active")
+	}
+}
+
+func TestParseCertificate(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keyPair := testKeyPair(t, ecKey)
+
+	valid := &Certificate{
+		Key:           &Key{ID: testKeyID, KeyPair: keyPair},
+		ProviderID:    "layer:///providers/0a2b0ed8-8a0f-11e7-bb31-be2e44b06b34",
+		ApplicationID: "layer:///apps/staging/1b9e5c7e-8a0f-11e7-bb31-be2e44b06b34",
+	}
+	data, _ := json.Marshal(valid)
+	c, err := ParseCertificate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != testKeyID || c.KeyPair.Private != keyPair.Private {
+		t.Fatalf("Unexpected certificate %+v", c)
+	}
+
+	tests := []struct {
+		json  string
+		field string
+	}{
+		{`{"api_key":"key"}`, "application_id"},
+		{`{"application_id":"app","api_key":"key"}`, "application_id"},
+		{`{"application_id":"1b9e5c7e-8a0f-11e7-bb31-be2e44b06b34"}`, "api_key"},
+		{`{"application_id":"1b9e5c7e-8a0f-11e7-bb31-be2e44b06b34","key_id":"` + testKeyID + `","key_pair":{"private":"x"}}`, "provider_id"},
+	}
+	for _, test := range tests {
+		_, err := ParseCertificate([]byte(test.json))
+		if err == nil || !strings.Contains(err.Error(), test.field) {
+			t.Errorf("Expected an error about %s for %s, got %v", test.field, test.json, err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package common
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Logger is a structured logger taking a message followed by alternating
+// key/value pairs.  *slog.Logger satisfies this interface.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NopLogger discards all log output
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+// Redacted replaces secret values in log output
+const Redacted = "REDACTED"
+
+var (
+	// Identity tokens and other JWTs
+	jwtRE = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+
+	// PEM encoded private keys
+	privateKeyRE = regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)
+
+	// Bearer tokens and session tokens in Authorization headers
+	bearerRE       = regexp.MustCompile(`(?i)(Bearer\s+)[^\s"',]+`)
+	sessionTokenRE = regexp.MustCompile(`(?i)(session[-_]token\s*[=:]\s*"?)[^\s"&,}]+`)
+	identityRE     = regexp.MustCompile(`(?i)("?identity_token"?\s*[=:]\s*"?)[^\s"&,}]+`)
+)
+
+// secretQueryParameters are URL query parameters whose values are redacted
+var secretQueryParameters = []string{"session_token", "identity_token", "access_token", "token"}
+
+// RedactString removes bearer tokens, session tokens, identity tokens and
+// private keys from a string
+func RedactString(s string) string {
+	s = privateKeyRE.ReplaceAllString(s, Redacted)
+	s = jwtRE.ReplaceAllString(s, Redacted)
+	s = bearerRE.ReplaceAllString(s, "${1}"+Redacted)
+	s = sessionTokenRE.ReplaceAllString(s, "${1}"+Redacted)
+	s = identityRE.ReplaceAllString(s, "${1}"+Redacted)
+	return s
+}
+
+// RedactURL removes secret query parameters from a URL
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	redacted := *u
+	redacted.User = nil
+	q := redacted.Query()
+	changed := false
+	for _, p := range secretQueryParameters {
+		if _, ok := q[p]; ok {
+			q.Set(p, Redacted)
+			changed = true
+		}
+	}
+	if changed {
+		redacted.RawQuery = q.Encode()
+	}
+	return redacted.String()
+}
+
+// RedactHeaders returns a copy of the headers with credentials removed
+func RedactHeaders(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for k, v := range h {
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie":
+			redacted[k] = []string{Redacted}
+		default:
+			values := make([]string, len(v))
+			for i, value := range v {
+				values[i] = RedactString(value)
+			}
+			redacted[k] = values
+		}
+	}
+	return redacted
+}
+
+// RedactLogger wraps a logger so that every message and value is redacted
+// before it is logged
+func RedactLogger(l Logger) Logger {
+	if l == nil {
+		return NopLogger
+	}
+	if _, ok := l.(redactingLogger); ok {
+		return l
+	}
+	return redactingLogger{l}
+}
+
+type redactingLogger struct {
+	logger Logger
+}
+
+func (l redactingLogger) Debug(msg string, args ...interface{}) {
+	l.logger.Debug(RedactString(msg), redactArgs(args)...)
+}
+
+func (l redactingLogger) Info(msg string, args ...interface{}) {
+	l.logger.Info(RedactString(msg), redactArgs(args)...)
+}
+
+func (l redactingLogger) Warn(msg string, args ...interface{}) {
+	l.logger.Warn(RedactString(msg), redactArgs(args)...)
+}
+
+func (l redactingLogger) Error(msg string, args ...interface{}) {
+	l.logger.Error(RedactString(msg), redactArgs(args)...)
+}
+
+// redactArgs redacts log arguments, which are key/value pairs or slog.Attr
+// values as accepted by *slog.Logger
+func redactArgs(args []interface{}) []interface{} {
+	redacted := make([]interface{}, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i].(type) {
+		case slog.Attr:
+			redacted = append(redacted, redactAttr(arg))
+		case string:
+			if i == len(args)-1 {
+				// A value without a key
+				redacted = append(redacted, RedactString(arg))
+				break
+			}
+			i++
+			if isSecretKey(arg) {
+				// Values logged under secret keys are always removed entirely
+				redacted = append(redacted, arg, Redacted)
+			} else {
+				redacted = append(redacted, arg, redactValue(args[i]))
+			}
+		default:
+			redacted = append(redacted, redactValue(arg))
+		}
+	}
+	return redacted
+}
+
+// redactAttr redacts the value of an attribute, including attributes in groups
+func redactAttr(a slog.Attr) slog.Attr {
+	if isSecretKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		return slog.Any(a.Key, redactValue(a.Value.Any()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		attrs := make([]interface{}, len(group))
+		for i, attr := range group {
+			attrs[i] = redactAttr(attr)
+		}
+		return slog.Group(a.Key, attrs...)
+	}
+	return a
+}
+
+// redactValue redacts strings, errors, URLs and headers, leaving other values
+// unchanged
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return RedactString(v)
+	case *url.URL:
+		return RedactURL(v)
+	case http.Header:
+		return RedactHeaders(v)
+	case error:
+		return errors.New(RedactString(v.Error()))
+	}
+	return value
+}
+
+// isSecretKey returns true for log keys whose values are always secret
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range []string{"token", "password", "private", "secret", "authorization"} {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"time"
+)
+
+// Metrics collects counters and timings from the Layer clients.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest records a completed HTTP request by endpoint family.  The
+	// status code is 0 if no response was received.
+	ObserveRequest(endpoint, method string, statusCode int, duration time.Duration)
+
+	// ObserveRateLimitWait records time a request spent waiting on the
+	// client-side rate limiter
+	ObserveRateLimitWait(endpoint string, duration time.Duration)
+
+	// ObserveTokenMint records the time taken to mint a session token
+	ObserveTokenMint(duration time.Duration, err error)
+
+	// IncWebsocketReconnect records a websocket reconnection
+	IncWebsocketReconnect()
+
+	// IncEvent records a websocket event by type, such as
+	// "Change.Message.create"
+	IncEvent(eventType string)
+
+	// ObserveHandler records the time a websocket event handler took to run
+	ObserveHandler(eventType string, duration time.Duration)
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// Presence statuses.  Users who are invisible appear offline to others.
+const (
+	PresenceAvailable = "available"
+	PresenceAway      = "away"
+	PresenceBusy      = "busy"
+	PresenceOffline   = "offline"
+	PresenceInvisible = "invisible"
+)
+
+// Presence is the online status of a user identity
+type Presence struct {
+	// Status is one of the Presence status constants
+	Status string `json:"status"`
+
+	// LastSeenAt is the time the user was last online
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// Online returns true if the user is connected, even if away or busy
+func (p *Presence) Online() bool {
+	if p == nil {
+		return false
+	}
+	switch p.Status {
+	case PresenceAvailable, PresenceAway, PresenceBusy:
+		return true
+	}
+	return false
+}
+
+// ValidatePresenceStatus returns an error if the status cannot be set
+func ValidatePresenceStatus(status string) error {
+	switch status {
+	case PresenceAvailable, PresenceAway, PresenceBusy, PresenceOffline, PresenceInvisible:
+		return nil
+	}
+	return fmt.Errorf("Invalid presence status %q", status)
+}
@@ -0,0 +1,28 @@
+package common
+
+import "testing"
+
+func TestPresence(t *testing.T) {
+	for status, online := range map[string]bool{
+		PresenceAvailable: true,
+		PresenceAway:      true,
+		PresenceBusy:      true,
+		PresenceOffline:   false,
+		PresenceInvisible: false,
+	} {
+		if err := ValidatePresenceStatus(status); err != nil {
+			t.Fatal(err)
+		}
+		if p := (&Presence{Status: status}); p.Online() != online {
+			t.Fatalf("Expected %s online to be %t", status, online)
+		}
+	}
+
+	if err := ValidatePresenceStatus("asleep"); err == nil {
+		t.Fatal("Expected an error for an invalid status")
+	}
+	var p *Presence
+	if p.Online() {
+		t.Fatal("Expected unknown presence to be offline")
+	}
+}
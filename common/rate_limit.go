@@ -0,0 +1,49 @@
+package common
+
+import (
+	"strings"
+)
+
+// Endpoint families group API endpoints that share a request budget
+const (
+	EndpointIdentities    = "identities"
+	EndpointConversations = "conversations"
+	EndpointMessages      = "messages"
+	EndpointAnnouncements = "announcements"
+	EndpointOther         = "other"
+)
+
+// RateLimit describes a token bucket rate limit
+type RateLimit struct {
+	// Rate is the sustained number of requests allowed per second
+	Rate float64
+
+	// Burst is the maximum number of requests that can be sent at once
+	Burst int
+}
+
+// EndpointFamily returns the endpoint family of a Client or Server API
+// request path
+func EndpointFamily(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	// Strip the Server API application prefix
+	if len(parts) >= 2 && parts[0] == "apps" {
+		parts = parts[2:]
+	}
+
+	family := EndpointOther
+	for _, part := range parts {
+		switch part {
+		case "identity", "identities":
+			return EndpointIdentities
+		case "messages":
+			return EndpointMessages
+		case "announcements", "notifications":
+			return EndpointAnnouncements
+		case "conversations":
+			family = EndpointConversations
+		}
+	}
+	return family
+}
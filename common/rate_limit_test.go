@@ -0,0 +1,26 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestEndpointFamily(t *testing.T) {
+	paths := map[string]string{
+		"/apps/APP_ID/users/test/identity":               EndpointIdentities,
+		"/apps/APP_ID/conversations":                     EndpointConversations,
+		"/apps/APP_ID/conversations/CONVO_ID":            EndpointConversations,
+		"/apps/APP_ID/conversations/CONVO_ID/messages":   EndpointMessages,
+		"/apps/APP_ID/users/test/conversations/CONVO_ID": EndpointConversations,
+		"/apps/APP_ID/announcements":                     EndpointAnnouncements,
+		"/apps/APP_ID/notifications":                     EndpointAnnouncements,
+		"/conversations/CONVO_ID/messages":               EndpointMessages,
+		"/identities/test":                               EndpointIdentities,
+		"/nonces":                                        EndpointOther,
+	}
+
+	for path, family := range paths {
+		if f := EndpointFamily(path); f != family {
+			t.Errorf("%s: expected %s, got %s", path, family, f)
+		}
+	}
+}
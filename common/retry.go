@@ -1 +1,141 @@
 package common
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader marks a request as safe to retry regardless of
+	// its method
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// RateLimitRemainingHeader and RateLimitResetHeader are returned by
+	// Layer when a request budget is exhausted, with the reset time given in
+	// seconds since the Unix epoch
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts.  Responses asking for a
+	// longer delay with Retry-After are returned without retrying.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff grows by after each attempt
+	Multiplier float64
+
+	// Jitter is the fraction (0 to 1) of each backoff that is randomized
+	Jitter float64
+
+	// StatusCodes contains the response status codes that are retried
+	StatusCodes []int
+}
+
+// DefaultRetryPolicy returns a policy retrying rate limited and unavailable
+// responses up to four times
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		StatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// DefaultWebsocketReconnectPolicy returns the policy used to reconnect a
+// dropped websocket, giving up after ten attempts.  StatusCodes is unused.
+func DefaultWebsocketReconnectPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+}
+
+// Backoff returns the delay before the given retry attempt, starting at 1
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		backoff = backoff*(1-jitter) + backoff*jitter*rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+// RetryableStatus returns true if responses with the status code should be
+// retried
+func (p *RetryPolicy) RetryableStatus(statusCode int) bool {
+	for _, code := range p.StatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryAfter returns the delay requested by the server in the Retry-After or
+// Layer rate limit headers
+func RetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := h.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			if t.Before(now) {
+				return 0, true
+			}
+			return t.Sub(now), true
+		}
+	}
+
+	if h.Get(RateLimitRemainingHeader) == "0" {
+		if reset, err := strconv.ParseInt(h.Get(RateLimitResetHeader), 10, 64); err == nil {
+			t := time.Unix(reset, 0)
+			if t.Before(now) {
+				return 0, true
+			}
+			return t.Sub(now), true
+		}
+	}
+
+	return 0, false
+}
+
+// IsIdempotent returns true if the request can safely be sent more than
+// once, either because of its method or because it carries an idempotency
+// key
+func IsIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+	}
+	for i, e := range expected {
+		if b := p.Backoff(i + 1); b != e {
+			t.Errorf("Attempt %d: expected backoff %v, got %v", i+1, e, b)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if b := p.Backoff(1); b < 50*time.Millisecond || b > 100*time.Millisecond {
+			t.Fatalf("Jittered backoff %v out of range", b)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Now()
+
+	h := http.Header{}
+	if _, ok := RetryAfter(h, now); ok {
+		t.Error("Expected no delay without headers")
+	}
+
+	h.Set("Retry-After", "3")
+	if d, ok := RetryAfter(h, now); !ok || d != 3*time.Second {
+		t.Errorf("Expected 3s delay, got %v", d)
+	}
+
+	h.Set("Retry-After", now.Add(10*time.Second).UTC().Format(http.TimeFormat))
+	if d, ok := RetryAfter(h, now); !ok || d < 9*time.Second || d > 10*time.Second {
+		t.Errorf("Expected ~10s delay, got %v", d)
+	}
+
+	h = http.Header{}
+	h.Set(RateLimitRemainingHeader, "0")
+	h.Set(RateLimitResetHeader, strconv.FormatInt(now.Add(5*time.Second).Unix(), 10))
+	if d, ok := RetryAfter(h, now); !ok || d < 4*time.Second || d > 5*time.Second {
+		t.Errorf("Expected ~5s delay, got %v", d)
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://api.layer.com", nil)
+	if !IsIdempotent(req) {
+		t.Error("Expected GET to be idempotent")
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "https://api.layer.com", nil)
+	if IsIdempotent(req) {
+		t.Error("Expected POST not to be idempotent")
+	}
+
+	req.Header.Set(IdempotencyKeyHeader, "key")
+	if !IsIdempotent(req) {
+		t.Error("Expected POST with an idempotency key to be idempotent")
+	}
+}
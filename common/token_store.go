@@ -0,0 +1,21 @@
+package common
+
+import (
+	"golang.org/x/net/context"
+)
+
+// TokenStore persists Layer session tokens so they can be reused across
+// restarts and shared between processes.  Tokens are keyed by application ID
+// and user.
+type TokenStore interface {
+	// Get returns the stored session token, or an empty string if no token is
+	// stored for the application and user
+	Get(ctx context.Context, appID, user string) (string, error)
+
+	// Put stores a session token for the application and user
+	Put(ctx context.Context, appID, user, token string) error
+
+	// Invalidate removes any stored session token for the application and
+	// user
+	Invalidate(ctx context.Context, appID, user string) error
+}
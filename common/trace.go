@@ -0,0 +1,72 @@
+package common
+
+import (
+	"golang.org/x/net/context"
+)
+
+// Span attribute keys recorded for Client and Server API operations
+const (
+	SpanAttributeHTTPMethod      = "http.method"
+	SpanAttributeHTTPURL         = "http.url"
+	SpanAttributeHTTPStatusCode  = "http.status_code"
+	SpanAttributeRequestID       = "layer.request_id"
+	SpanAttributeRetries         = "layer.retries"
+	SpanAttributeWebsocketMethod = "layer.websocket.method"
+)
+
+// RequestIDHeaders are the response headers that may carry the Layer request
+// ID
+var RequestIDHeaders = []string{"X-Layer-Request-Id", "X-Request-Id"}
+
+// Tracer starts spans for Client and Server API operations
+type Tracer interface {
+	// StartSpan starts a span for the named operation (for example
+	// "server.CreateConversation"), returning a context carrying the span
+	StartSpan(ctx context.Context, operation string) (context.Context, Span)
+}
+
+// Span records the timing and outcome of a single operation
+type Span interface {
+	// SetAttribute records a key/value attribute on the span
+	SetAttribute(key string, value interface{})
+
+	// SetError marks the span as failed
+	SetError(err error)
+
+	// End completes the span
+	End()
+}
+
+type spanContextKey struct{}
+
+// StartSpan starts a span with the given tracer and stores it in the
+// returned context so it can be annotated by the transport.  A nil tracer
+// returns a span that does nothing.
+func StartSpan(ctx context.Context, tracer Tracer, operation string) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := tracer.StartSpan(ctx, operation)
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// SpanFromContext returns the span stored in the context, or a span that does
+// nothing if there is none
+func SpanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanContextKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// HasSpan returns true if the context carries a span
+func HasSpan(ctx context.Context) bool {
+	_, ok := ctx.Value(spanContextKey{}).(Span)
+	return ok
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) SetError(err error)                         {}
+func (noopSpan) End()                                       {}
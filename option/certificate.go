@@ -0,0 +1,128 @@
+package option
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/layerhq/go-client/common"
+)
+
+// Environment variables read by FromEnvironment
+const (
+	EnvCertificate   = "LAYER_CERTIFICATE"
+	EnvApplicationID = "LAYER_APP_ID"
+	EnvProviderID    = "LAYER_PROVIDER_ID"
+	EnvAccountID     = "LAYER_ACCOUNT_ID"
+	EnvKeyID         = "LAYER_KEY_ID"
+	EnvPrivateKey    = "LAYER_PRIVATE_KEY"
+	EnvAPIKey        = "LAYER_API_KEY"
+)
+
+// WithCertificate returns a ClientOption that authenticates with a Layer
+// certificate.  The Client API signs identity tokens with the certificate key
+// pair, and the Server API uses the certificate API key as a bearer token.
+// The certificate application ID is used if none is passed to NewClient.
+//
+// A certificate does not name a user, so the Client API still requires
+// WithCredentials with a User; creating a client without one fails.
+func WithCertificate(c *common.Certificate) ClientOption {
+	if c == nil {
+		return withCertificate{err: fmt.Errorf("Certificate is empty")}
+	}
+	if err := common.ValidateCertificate(c); err != nil {
+		return withCertificate{err: err}
+	}
+	return withCertificate{certificate: c}
+}
+
+// WithCertificateFile returns a ClientOption that authenticates with a Layer
+// certificate read from a JSON file
+func WithCertificateFile(path string) ClientOption {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return withCertificate{err: fmt.Errorf("Error reading certificate file: %v", err)}
+	}
+	return WithCertificateJSON(data)
+}
+
+// WithCertificateJSON returns a ClientOption that authenticates with a Layer
+// certificate in JSON format
+func WithCertificateJSON(data []byte) ClientOption {
+	c, err := common.ParseCertificate(data)
+	return withCertificate{certificate: c, err: err}
+}
+
+// FromEnvironment returns a ClientOption that authenticates with the
+// certificate file named by LAYER_CERTIFICATE or, if that is not set, with
+// the certificate fields in LAYER_APP_ID, LAYER_PROVIDER_ID,
+// LAYER_ACCOUNT_ID, LAYER_KEY_ID, LAYER_PRIVATE_KEY and LAYER_API_KEY.
+func FromEnvironment() ClientOption {
+	if path := os.Getenv(EnvCertificate); path != "" {
+		return WithCertificateFile(path)
+	}
+
+	c := &common.Certificate{
+		ApplicationID: os.Getenv(EnvApplicationID),
+		ProviderID:    os.Getenv(EnvProviderID),
+		AccountID:     os.Getenv(EnvAccountID),
+		APIKey:        os.Getenv(EnvAPIKey),
+	}
+	if keyID, private := os.Getenv(EnvKeyID), os.Getenv(EnvPrivateKey); keyID != "" || private != "" {
+		// Allow keys with escaped newlines, which many environments require
+		if !strings.Contains(private, "\n") {
+			private = strings.Replace(private, `\n`, "\n", -1)
+		}
+		c.Key = &common.Key{
+			ID:      keyID,
+			KeyPair: &common.KeyPair{Private: private},
+		}
+	}
+
+	if c.ApplicationID == "" && c.Key == nil && c.APIKey == "" {
+		return withCertificate{err: fmt.Errorf("No Layer credentials found in the environment")}
+	}
+	return WithCertificate(c)
+}
+
+// WithKeyRing returns a ClientOption that signs identity tokens with the
+// active key of a key ring, replacing the key of the client credentials so
+// that provider keys can be rotated without downtime.  Every key in the ring
+// must be valid.
+func WithKeyRing(ring common.KeyRing) ClientOption {
+	if err := ring.Validate(); err != nil {
+		return withKeyRing{err: err}
+	}
+	return withKeyRing{ring: ring}
+}
+
+type withKeyRing struct {
+	ring common.KeyRing
+	err  error
+}
+
+func (w withKeyRing) Apply(s *common.DialSettings) {
+	if w.err != nil {
+		if s.Err == nil {
+			s.Err = w.err
+		}
+		return
+	}
+	s.KeyRing = w.ring
+}
+
+type withCertificate struct {
+	certificate *common.Certificate
+	err         error
+}
+
+func (w withCertificate) Apply(s *common.DialSettings) {
+	if w.err != nil {
+		if s.Err == nil {
+			s.Err = w.err
+		}
+		return
+	}
+	s.Certificate = w.certificate
+}
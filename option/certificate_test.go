@@ -0,0 +1,43 @@
+package option
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/layerhq/go-client/common"
+)
+
+func TestFromEnvironment(t *testing.T) {
+	t.Setenv(EnvCertificate, "")
+	t.Setenv(EnvApplicationID, "1b9e5c7e-8a0f-11e7-bb31-be2e44b06b34")
+	t.Setenv(EnvAPIKey, "api-key")
+
+	s := NewDialSettings(FromEnvironment())
+	if s.Err != nil {
+		t.Fatal(s.Err)
+	}
+	if s.Certificate == nil || s.Certificate.APIKey != "api-key" {
+		t.Fatalf("Expected certificate from the environment, got %+v", s.Certificate)
+	}
+	if s.ApplicationID("") != "1b9e5c7e-8a0f-11e7-bb31-be2e44b06b34" {
+		t.Fatalf("Expected application ID from the certificate, got %s", s.ApplicationID(""))
+	}
+}
+
+func TestWithCertificateFileMissing(t *testing.T) {
+	s := NewDialSettings(WithCertificateFile("/nonexistent/certificate.json"))
+	if s.Err == nil || !strings.Contains(s.Err.Error(), "certificate file") {
+		t.Fatalf("Expected certificate file error, got %v", s.Err)
+	}
+}
+
+func TestWithKeyRingInvalid(t *testing.T) {
+	ring := common.KeyRing{{ID: "7f3e0b4a-8a0e-11e7-bb31-be2e44b06b34"}}
+	s := NewDialSettings(WithKeyRing(ring))
+	if s.Err == nil || !strings.Contains(s.Err.Error(), "key pair") {
+		t.Fatalf("Expected key ring error, got %v", s.Err)
+	}
+	if s.KeyRing != nil {
+		t.Fatalf("Expected the invalid key ring not to be set, got %v", s.KeyRing)
+	}
+}
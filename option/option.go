@@ -2,6 +2,9 @@
 package option
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
 	"net/url"
 
 	"github.com/layerhq/go-client/common"
@@ -12,6 +15,18 @@ type ClientOption interface {
 	Apply(*common.DialSettings)
 }
 
+// NewDialSettings returns the dial settings for the given options
+func NewDialSettings(opts ...ClientOption) *common.DialSettings {
+	var s common.DialSettings
+	for _, opt := range opts {
+		opt.Apply(&s)
+	}
+	return &s
+}
+
+// OverrideURL returns a ClientOption that sets the API base URL, for
+// regional or self-hosted endpoints.  The Server API appends the application
+// path to it.
 func OverrideURL(u *url.URL) ClientOption {
 	return overrideURL{u}
 }
@@ -22,8 +37,56 @@ func (o overrideURL) Apply(s *common.DialSettings) {
 	s.BaseURL = o.baseURL
 }
 
+// WithWebsocketURL returns a ClientOption that sets the Client API websocket
+// URL
+func WithWebsocketURL(u *url.URL) ClientOption {
+	return withWebsocketURL{u}
+}
+
+type withWebsocketURL struct{ websocketURL *url.URL }
+
+func (w withWebsocketURL) Apply(s *common.DialSettings) {
+	s.WebsocketURL = w.websocketURL
+}
+
+// WithAPIVersion returns a ClientOption that sets the API version requested
+// in the Accept header, such as "3.0"
+func WithAPIVersion(version string) ClientOption {
+	return withAPIVersion{version}
+}
+
+type withAPIVersion struct{ version string }
+
+func (w withAPIVersion) Apply(s *common.DialSettings) {
+	s.APIVersion = w.version
+}
+
+// WithWebsocketOrigin returns a ClientOption that sets the Origin header sent
+// in the websocket handshake
+func WithWebsocketOrigin(origin string) ClientOption {
+	return withWebsocketOrigin{origin}
+}
+
+type withWebsocketOrigin struct{ origin string }
+
+func (w withWebsocketOrigin) Apply(s *common.DialSettings) {
+	s.WebsocketOrigin = w.origin
+}
+
+// WithWebsocketSubprotocol returns a ClientOption that sets the subprotocol
+// requested in the websocket handshake, such as "layer-3.0"
+func WithWebsocketSubprotocol(protocol string) ClientOption {
+	return withWebsocketSubprotocol{protocol}
+}
+
+type withWebsocketSubprotocol struct{ protocol string }
+
+func (w withWebsocketSubprotocol) Apply(s *common.DialSettings) {
+	s.WebsocketSubprotocol = w.protocol
+}
+
 // AllowInsecure skips TLS verification (this is very likely only useful
-// during testing)
+// during testing).  The setting only applies to the client it is passed to.
 func AllowInsecure() ClientOption {
 	return allowInsecure{}
 }
@@ -45,6 +108,27 @@ func (w withHeaders) Apply(s *common.DialSettings) {
 	s.Headers = w.headers
 }
 
+// WithDefaultHeaders returns a ClientOption that adds headers which have not
+// already been set with WithHeaders
+func WithDefaultHeaders(headers map[string][]string) ClientOption {
+	return withDefaultHeaders{headers}
+}
+
+type withDefaultHeaders struct{ headers map[string][]string }
+
+func (w withDefaultHeaders) Apply(s *common.DialSettings) {
+	merged := make(map[string][]string, len(s.Headers)+len(w.headers))
+	for k, v := range s.Headers {
+		merged[http.CanonicalHeaderKey(k)] = v
+	}
+	for k, v := range w.headers {
+		if _, ok := merged[http.CanonicalHeaderKey(k)]; !ok {
+			merged[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	s.Headers = merged
+}
+
 // WithBearerToken returns a ClientOption that specifies a bearer token
 // string to be used for authentication.
 func WithBearerToken(token string) ClientOption {
@@ -57,8 +141,24 @@ func (w withBearerToken) Apply(s *common.DialSettings) {
 	s.BearerToken = w.token
 }
 
+// WithBearerTokenSource returns a ClientOption that takes bearer tokens from
+// a source, such as a secrets manager that rotates API keys.  Tokens are
+// cached for a few minutes, and the source is consulted again as soon as a
+// request is rejected.
+func WithBearerTokenSource(source common.BearerTokenSource) ClientOption {
+	return withBearerTokenSource{source}
+}
+
+type withBearerTokenSource struct{ source common.BearerTokenSource }
+
+func (w withBearerTokenSource) Apply(s *common.DialSettings) {
+	s.BearerTokenSource = w.source
+}
+
 // WithSessionToken returns a ClientOption that specifies a session token
-// string to be used for authentication.
+// string to be used for authentication.  If a token function or credentials
+// are also specified, they are used to mint a new session once the supplied
+// session token is rejected.
 func WithSessionToken(token string) ClientOption {
 	return withSessionToken{token}
 }
@@ -84,6 +184,26 @@ func (w withTokenFunc) Apply(s *common.DialSettings) {
 	s.TokenFunc = w.tokenFunc
 }
 
+// WithRemoteTokenProvider returns a ClientOption that requests identity
+// tokens from a remote identity provider, such as a handler from the provider
+// package.  Credentials naming the user are also required.
+func WithRemoteTokenProvider(url string) ClientOption {
+	return WithRemoteTokenProviderConfig(&common.RemoteTokenProvider{URL: url})
+}
+
+// WithRemoteTokenProviderConfig returns a ClientOption that requests
+// identity tokens from a remote identity provider with custom headers,
+// timeouts and retries.
+func WithRemoteTokenProviderConfig(p *common.RemoteTokenProvider) ClientOption {
+	return withRemoteTokenProvider{p}
+}
+
+type withRemoteTokenProvider struct{ provider *common.RemoteTokenProvider }
+
+func (w withRemoteTokenProvider) Apply(s *common.DialSettings) {
+	s.RemoteTokenProvider = w.provider
+}
+
 // WithCredentials returns a ClientOption that specifies explicit client
 // credentials to be used for authentication.
 func WithCredentials(c *common.ClientCredentials) ClientOption {
@@ -95,3 +215,211 @@ type withCredentials struct{ credentials *common.ClientCredentials }
 func (w withCredentials) Apply(s *common.DialSettings) {
 	s.ClientCredentials = w.credentials
 }
+
+// WithTokenStore returns a ClientOption that specifies a store used to
+// persist and share session tokens, avoiding a new session being minted every
+// time a client is created.
+func WithTokenStore(store common.TokenStore) ClientOption {
+	return withTokenStore{store}
+}
+
+type withTokenStore struct{ store common.TokenStore }
+
+func (w withTokenStore) Apply(s *common.DialSettings) {
+	s.TokenStore = w.store
+}
+
+// WithRetryPolicy returns a ClientOption that retries idempotent requests
+// failing with rate limited, unavailable or connection errors according to
+// the given policy.  Use common.DefaultRetryPolicy for sensible defaults.
+func WithRetryPolicy(policy *common.RetryPolicy) ClientOption {
+	return withRetryPolicy{policy}
+}
+
+type withRetryPolicy struct{ policy *common.RetryPolicy }
+
+func (w withRetryPolicy) Apply(s *common.DialSettings) {
+	s.RetryPolicy = w.policy
+}
+
+// WithWebsocketReconnect returns a ClientOption that sets how a dropped
+// websocket is reconnected.  Attempts are counted from the first reconnect,
+// and a MaxAttempts of zero retries forever.  Defaults to
+// common.DefaultWebsocketReconnectPolicy.
+func WithWebsocketReconnect(policy *common.RetryPolicy) ClientOption {
+	return withWebsocketReconnect{policy}
+}
+
+type withWebsocketReconnect struct{ policy *common.RetryPolicy }
+
+func (w withWebsocketReconnect) Apply(s *common.DialSettings) {
+	s.WebsocketReconnect = w.policy
+}
+
+// WithCircuitBreaker returns a ClientOption that fails requests fast with
+// common.ErrCircuitOpen while Layer is failing, tracked separately for each
+// host and endpoint family.  Use common.DefaultCircuitBreaker for the default
+// settings.
+func WithCircuitBreaker(breaker *common.CircuitBreaker) ClientOption {
+	return withCircuitBreaker{breaker}
+}
+
+type withCircuitBreaker struct{ breaker *common.CircuitBreaker }
+
+func (w withCircuitBreaker) Apply(s *common.DialSettings) {
+	s.CircuitBreaker = w.breaker
+}
+
+// WithResponseCache returns a ClientOption that caches GET responses with
+// an ETag or Last-Modified header, revalidating them on repeated requests.
+// Updates and deletes made through the client invalidate the cached object.
+// Use common.DefaultResponseCache for the default limits.
+func WithResponseCache(cache *common.ResponseCache) ClientOption {
+	return withResponseCache{cache}
+}
+
+type withResponseCache struct{ cache *common.ResponseCache }
+
+func (w withResponseCache) Apply(s *common.DialSettings) {
+	s.ResponseCache = w.cache
+}
+
+// WithRateLimit returns a ClientOption that paces all requests to the given
+// number of requests per second, allowing bursts of up to burst requests.
+func WithRateLimit(rate float64, burst int) ClientOption {
+	return withRateLimit{&common.RateLimit{Rate: rate, Burst: burst}}
+}
+
+type withRateLimit struct{ limit *common.RateLimit }
+
+func (w withRateLimit) Apply(s *common.DialSettings) {
+	s.RateLimit = w.limit
+}
+
+// WithEndpointRateLimit returns a ClientOption that paces requests to an
+// endpoint family (common.EndpointIdentities, common.EndpointConversations,
+// common.EndpointMessages or common.EndpointAnnouncements) in addition to any
+// global rate limit.
+func WithEndpointRateLimit(family string, rate float64, burst int) ClientOption {
+	return withEndpointRateLimit{family, &common.RateLimit{Rate: rate, Burst: burst}}
+}
+
+type withEndpointRateLimit struct {
+	family string
+	limit  *common.RateLimit
+}
+
+func (w withEndpointRateLimit) Apply(s *common.DialSettings) {
+	if s.EndpointRateLimits == nil {
+		s.EndpointRateLimits = make(map[string]*common.RateLimit)
+	}
+	s.EndpointRateLimits[w.family] = w.limit
+}
+
+// WithHTTPClient returns a ClientOption that sends requests using the
+// transport, timeout, cookie jar and redirect policy of the given client.
+// The supplied client is not modified.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return withHTTPClient{client}
+}
+
+type withHTTPClient struct{ client *http.Client }
+
+func (w withHTTPClient) Apply(s *common.DialSettings) {
+	s.HTTPClient = w.client
+}
+
+// WithBaseTransport returns a ClientOption that specifies the underlying
+// transport used to send requests, in place of transport.DefaultTransport.
+// This takes precedence over the transport of a client set by WithHTTPClient.
+func WithBaseTransport(rt http.RoundTripper) ClientOption {
+	return withBaseTransport{rt}
+}
+
+type withBaseTransport struct{ rt http.RoundTripper }
+
+func (w withBaseTransport) Apply(s *common.DialSettings) {
+	s.BaseTransport = w.rt
+}
+
+// WithRootCAs returns a ClientOption that verifies server certificates for
+// REST and websocket connections against the given certificate pool instead
+// of the system roots.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return withRootCAs{pool}
+}
+
+type withRootCAs struct{ pool *x509.CertPool }
+
+func (w withRootCAs) Apply(s *common.DialSettings) {
+	s.RootCAs = w.pool
+}
+
+// WithClientCertificate returns a ClientOption that presents the given
+// certificate for mutual TLS on REST and websocket connections.  It may be
+// specified more than once.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return withClientCertificate{cert}
+}
+
+type withClientCertificate struct{ cert tls.Certificate }
+
+func (w withClientCertificate) Apply(s *common.DialSettings) {
+	s.ClientCertificates = append(s.ClientCertificates, w.cert)
+}
+
+// WithMiddleware returns a ClientOption that wraps the authenticating
+// transport with the given middleware, for example to log, sign or modify
+// requests.  Middleware is applied in order, so the first middleware sees
+// each request first.  The option may be specified more than once.
+func WithMiddleware(middleware ...func(http.RoundTripper) http.RoundTripper) ClientOption {
+	return withMiddleware{middleware}
+}
+
+type withMiddleware struct {
+	middleware []func(http.RoundTripper) http.RoundTripper
+}
+
+func (w withMiddleware) Apply(s *common.DialSettings) {
+	for _, m := range w.middleware {
+		s.Middleware = append(s.Middleware, m)
+	}
+}
+
+// WithTracer returns a ClientOption that reports a span for every Client and
+// Server API operation to the given tracer.
+func WithTracer(tracer common.Tracer) ClientOption {
+	return withTracer{tracer}
+}
+
+type withTracer struct{ tracer common.Tracer }
+
+func (w withTracer) Apply(s *common.DialSettings) {
+	s.Tracer = w.tracer
+}
+
+// WithMetrics returns a ClientOption that reports request, websocket and
+// session metrics to the given collector.
+func WithMetrics(metrics common.Metrics) ClientOption {
+	return withMetrics{metrics}
+}
+
+type withMetrics struct{ metrics common.Metrics }
+
+func (w withMetrics) Apply(s *common.DialSettings) {
+	s.Metrics = w.metrics
+}
+
+// WithLogger returns a ClientOption that logs request summaries and
+// connection events to the given structured logger, such as a *slog.Logger.
+// Bearer tokens, session tokens, identity tokens and private keys are always
+// redacted.
+func WithLogger(logger common.Logger) ClientOption {
+	return withLogger{logger}
+}
+
+type withLogger struct{ logger common.Logger }
+
+func (w withLogger) Apply(s *common.DialSettings) {
+	s.Logger = common.RedactLogger(w.logger)
+}
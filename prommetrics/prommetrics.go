@@ -0,0 +1,254 @@
+// Package prommetrics collects Layer client metrics and exposes them in the
+// Prometheus text exposition format.
+package prommetrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+)
+
+// DefaultBuckets are the histogram buckets, in seconds, used for all
+// durations
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Collector implements common.Metrics and serves the collected metrics over
+// HTTP.  Pass it to option.WithMetrics and mount it on a metrics endpoint.
+type Collector struct {
+	namespace string
+	buckets   []float64
+
+	requests          *counterVec
+	requestDuration   *histogramVec
+	rateLimitWait     *counterVec
+	tokenMints        *counterVec
+	tokenMintDuration *histogramVec
+	reconnects        *counterVec
+	events            *counterVec
+	handlerDuration   *histogramVec
+}
+
+// NewCollector creates a collector with metric names prefixed by the given
+// namespace (such as "layer")
+func NewCollector(namespace string) *Collector {
+	c := &Collector{
+		namespace: namespace,
+		buckets:   DefaultBuckets,
+	}
+	c.requests = c.newCounterVec("requests_total", "Layer API requests by endpoint family, method and status code.", "endpoint", "method", "status")
+	c.requestDuration = c.newHistogramVec("request_duration_seconds", "Layer API request latency by endpoint family.", "endpoint")
+	c.rateLimitWait = c.newCounterVec("rate_limit_wait_seconds_total", "Time spent waiting on the client-side rate limiter by endpoint family.", "endpoint")
+	c.tokenMints = c.newCounterVec("token_mints_total", "Session tokens minted by result.", "result")
+	c.tokenMintDuration = c.newHistogramVec("token_mint_duration_seconds", "Session token mint latency.")
+	c.reconnects = c.newCounterVec("websocket_reconnects_total", "Websocket reconnections.")
+	c.events = c.newCounterVec("websocket_events_total", "Websocket events received by type.", "type")
+	c.handlerDuration = c.newHistogramVec("websocket_handler_duration_seconds", "Websocket event handler latency by event type.", "type")
+	return c
+}
+
+func (c *Collector) ObserveRequest(endpoint, method string, statusCode int, duration time.Duration) {
+	c.requests.add(1, endpoint, method, strconv.Itoa(statusCode))
+	c.requestDuration.observe(duration.Seconds(), endpoint)
+}
+
+func (c *Collector) ObserveRateLimitWait(endpoint string, duration time.Duration) {
+	c.rateLimitWait.add(duration.Seconds(), endpoint)
+}
+
+func (c *Collector) ObserveTokenMint(duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	c.tokenMints.add(1, result)
+	c.tokenMintDuration.observe(duration.Seconds())
+}
+
+func (c *Collector) IncWebsocketReconnect() {
+	c.reconnects.add(1)
+}
+
+func (c *Collector) IncEvent(eventType string) {
+	c.events.add(1, eventType)
+}
+
+func (c *Collector) ObserveHandler(eventType string, duration time.Duration) {
+	c.handlerDuration.observe(duration.Seconds(), eventType)
+}
+
+// ServeHTTP writes the collected metrics in the Prometheus text format
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	c.requests.write(&buf)
+	c.requestDuration.write(&buf)
+	c.rateLimitWait.write(&buf)
+	c.tokenMints.write(&buf)
+	c.tokenMintDuration.write(&buf)
+	c.reconnects.write(&buf)
+	c.events.write(&buf)
+	c.handlerDuration.write(&buf)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func (c *Collector) metricName(name string) string {
+	if c.namespace == "" {
+		return name
+	}
+	return c.namespace + "_" + name
+}
+
+// counterVec is a set of counters partitioned by label values
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+	values map[string]float64
+	mu     sync.Mutex
+}
+
+func (c *Collector) newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{
+		name:   c.metricName(name),
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+}
+
+func (v *counterVec) add(delta float64, labelValues ...string) {
+	key := formatLabels(v.labels, labelValues)
+	v.mu.Lock()
+	v.values[key] += delta
+	v.mu.Unlock()
+}
+
+func (v *counterVec) write(buf *bytes.Buffer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n", v.name, v.help, v.name)
+	for _, key := range sortedKeys(v.values) {
+		fmt.Fprintf(buf, "%s%s %s\n", v.name, key, formatValue(v.values[key]))
+	}
+}
+
+// histogramVec is a set of histograms partitioned by label values
+type histogramVec struct {
+	name       string
+	help       string
+	labels     []string
+	buckets    []float64
+	histograms map[string]*histogram
+	mu         sync.Mutex
+}
+
+type histogram struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+func (c *Collector) newHistogramVec(name, help string, labels ...string) *histogramVec {
+	return &histogramVec{
+		name:       c.metricName(name),
+		help:       help,
+		labels:     labels,
+		buckets:    c.buckets,
+		histograms: make(map[string]*histogram),
+	}
+}
+
+func (v *histogramVec) observe(value float64, labelValues ...string) {
+	key := formatLabels(v.labels, labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	h, ok := v.histograms[key]
+	if !ok {
+		h = &histogram{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(v.buckets)),
+		}
+		v.histograms[key] = h
+	}
+	for i, bound := range v.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+func (v *histogramVec) write(buf *bytes.Buffer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s histogram\n", v.name, v.help, v.name)
+	keys := make([]string, 0, len(v.histograms))
+	for key := range v.histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	bucketLabels := append(append([]string{}, v.labels...), "le")
+	for _, key := range keys {
+		h := v.histograms[key]
+		for i, bound := range v.buckets {
+			labels := formatLabels(bucketLabels, append(append([]string{}, h.labelValues...), formatValue(bound)))
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", v.name, labels, h.counts[i])
+		}
+		labels := formatLabels(bucketLabels, append(append([]string{}, h.labelValues...), "+Inf"))
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", v.name, labels, h.count)
+		fmt.Fprintf(buf, "%s_sum%s %s\n", v.name, key, formatValue(h.sum))
+		fmt.Fprintf(buf, "%s_count%s %d\n", v.name, key, h.count)
+	}
+}
+
+// formatLabels renders a label set such as {endpoint="messages"}
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var _ common.Metrics = &Collector{}
@@ -0,0 +1,42 @@
+package prommetrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector("layer")
+	c.ObserveRequest("messages", "POST", 201, 20*time.Millisecond)
+	c.ObserveRequest("messages", "POST", 201, 2*time.Second)
+	c.ObserveTokenMint(time.Second, errors.New("failed"))
+	c.IncWebsocketReconnect()
+	c.IncEvent("Change.Message.create")
+	c.ObserveHandler("Change.Message.create", time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	output := string(body)
+
+	expected := []string{
+		"# TYPE layer_requests_total counter",
+		`layer_requests_total{endpoint="messages",method="POST",status="201"} 2`,
+		`layer_request_duration_seconds_bucket{endpoint="messages",le="0.025"} 1`,
+		`layer_request_duration_seconds_bucket{endpoint="messages",le="+Inf"} 2`,
+		`layer_request_duration_seconds_count{endpoint="messages"} 2`,
+		`layer_token_mints_total{result="error"} 1`,
+		"layer_websocket_reconnects_total 1",
+		`layer_websocket_events_total{type="Change.Message.create"} 1`,
+		`layer_websocket_handler_duration_seconds_count{type="Change.Message.create"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected output to contain %q\n%s", line, output)
+		}
+	}
+}
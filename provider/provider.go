@@ -0,0 +1,105 @@
+// Package provider implements an identity provider that mints Layer
+// identity tokens for authenticated users.  Clients request tokens from it
+// with option.WithRemoteTokenProvider.
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+	"github.com/layerhq/go-client/transport"
+)
+
+// maxRequestSize limits the size of identity token requests
+const maxRequestSize = 64 * 1024
+
+// An Authenticator authenticates identity token requests
+type Authenticator interface {
+	// Authenticate returns the Layer user ID of the caller, or an error if
+	// the caller cannot be authenticated
+	Authenticate(r *http.Request) (string, error)
+}
+
+// AuthenticatorFunc adapts a function to the Authenticator interface
+type AuthenticatorFunc func(r *http.Request) (string, error)
+
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (string, error) {
+	return f(r)
+}
+
+// Handler mints identity tokens for users authenticated by its
+// Authenticator, signed with the provider credentials
+type Handler struct {
+	credentials   *common.ClientCredentials
+	authenticator Authenticator
+}
+
+// NewHandler creates an identity token handler.  The credentials must
+// include the provider ID and a key or key ring.
+func NewHandler(credentials *common.ClientCredentials, authenticator Authenticator) (*Handler, error) {
+	if credentials == nil || credentials.ProviderID == "" {
+		return nil, fmt.Errorf("Provider credentials must include a provider ID")
+	}
+	if _, err := credentials.SigningKey(time.Now()); err != nil {
+		return nil, err
+	}
+	if authenticator == nil {
+		return nil, fmt.Errorf("No authenticator specified")
+	}
+
+	return &Handler{
+		credentials:   credentials,
+		authenticator: authenticator,
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Identity tokens must be requested with POST")
+		return
+	}
+
+	var req common.IdentityTokenRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Error parsing identity token request")
+		return
+	}
+	if req.Nonce == "" {
+		writeError(w, http.StatusBadRequest, "missing_property", "A nonce is required")
+		return
+	}
+
+	user, err := h.authenticator.Authenticate(r)
+	if err != nil || user == "" {
+		writeError(w, http.StatusUnauthorized, "authentication_required", "The caller could not be authenticated")
+		return
+	}
+	if req.UserID != "" && req.UserID != user {
+		writeError(w, http.StatusForbidden, "access_denied", "The caller cannot request tokens for another user")
+		return
+	}
+
+	credentials := *h.credentials
+	credentials.User = user
+	token, err := transport.SignIdentityToken(&credentials, req.Nonce)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_server_error", "Error signing identity token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(common.IdentityTokenResponse{IdentityToken: token})
+}
+
+// writeError writes an error in the Layer error format
+func writeError(w http.ResponseWriter, statusCode int, id, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(common.RequestError{ID: id, Message: message})
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/layerhq/go-client/common"
+	"github.com/layerhq/go-client/option"
+	"github.com/layerhq/go-client/transport"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"golang.org/x/net/context"
+)
+
+func testCredentials(t *testing.T) (*common.ClientCredentials, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &common.ClientCredentials{
+		ProviderID: "layer:///providers/0a2b0ed8-8a0f-11e7-bb31-be2e44b06b34",
+		Key: &common.Key{
+			ID: "7f3e0b4a-8a0e-11e7-bb31-be2e44b06b34",
+			KeyPair: &common.KeyPair{
+				Private: string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+			},
+		},
+	}, key
+}
+
+// headerAuthenticator authenticates callers by the X-User header
+var headerAuthenticator = AuthenticatorFunc(func(r *http.Request) (string, error) {
+	if user := r.Header.Get("X-User"); user != "" {
+		return user, nil
+	}
+	return "", errors.New("Not authenticated")
+})
+
+func TestHandler(t *testing.T) {
+	credentials, key := testCredentials(t)
+	h, err := NewHandler(credentials, headerAuthenticator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	// Request a token through the remote token provider option
+	u, _ := url.Parse(s.URL)
+	tr, err := transport.NewHTTPTransport(context.Background(), "app", u, nil,
+		option.WithCredentials(&common.ClientCredentials{User: "alice"}),
+		option.WithRemoteTokenProviderConfig(&common.RemoteTokenProvider{
+			URL:     s.URL,
+			Headers: map[string][]string{"X-User": {"alice"}},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenFunc := tr.DialSettings().TokenFunc
+	signed, err := tokenFunc("alice", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	if claims["prn"] != "alice" || claims["nce"] != "nonce" || claims["iss"] != credentials.ProviderID {
+		t.Fatalf("Unexpected claims %v", claims)
+	}
+	if token.Header["cty"] != "layer-eit;v=1" {
+		t.Fatalf("Unexpected header %v", token.Header)
+	}
+
+	// Tokens cannot be requested for other users
+	if _, err := tokenFunc("bob", "nonce"); !errors.Is(err, common.ErrForbidden) {
+		t.Fatalf("Expected a forbidden error, got %v", err)
+	}
+}
+
+func TestHandlerUnauthenticated(t *testing.T) {
+	credentials, _ := testCredentials(t)
+	h, err := NewHandler(credentials, headerAuthenticator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	tr, err := transport.NewHTTPTransport(context.Background(), "app", u, nil,
+		option.WithCredentials(&common.ClientCredentials{User: "alice"}),
+		option.WithRemoteTokenProvider(s.URL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.DialSettings().TokenFunc("alice", "nonce"); !errors.Is(err, common.ErrAuthentication) {
+		t.Fatalf("Expected an authentication error, got %v", err)
+	}
+}
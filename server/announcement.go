@@ -54,6 +54,9 @@ func (s *Server) buildNotificationURL(id string) (u *url.URL, err error) {
 
 // SendAnnouncement sends an announcement
 func (s *Server) SendAnnouncement(ctx context.Context, sender string, recipients []string, parts []*common.MessagePart, notification *common.MessageNotification) (*Announcement, error) {
+	ctx, span := s.startSpan(ctx, "server.SendAnnouncement")
+	defer span.End()
+
 	// Create the request URL
 	u, err := s.buildAnnouncementURL("")
 	if err != nil {
@@ -87,12 +90,12 @@ func (s *Server) SendAnnouncement(ctx context.Context, sender string, recipients
 	// Send the request
 	res, err := s.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error sending request: %v", err)
+		return nil, fmt.Errorf("Error sending request: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusAccepted {
-		return nil, fmt.Errorf("Status is %d", res.StatusCode)
+		return nil, common.ResponseError(res)
 	}
 
 	var announcement *Announcement
@@ -107,6 +110,9 @@ func (s *Server) SendTextAnnouncement(ctx context.Context, sender string, recipi
 }
 
 func (s *Server) SendNotification(ctx context.Context, notification *NotificationCreate) error {
+	ctx, span := s.startSpan(ctx, "server.SendNotification")
+	defer span.End()
+
 	for i := range notification.Recipients {
 		notification.Recipients[i] = common.LayerURL(common.IdentitiesName, notification.Recipients[i])
 	}
@@ -131,12 +137,12 @@ func (s *Server) SendNotification(ctx context.Context, notification *Notificatio
 	// Send the request
 	res, err := s.transport.Do(req)
 	if err != nil {
-		return fmt.Errorf("Error sending request: %v", err)
+		return fmt.Errorf("Error sending request: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("Status is %d", res.StatusCode)
+		return common.ResponseError(res)
 	}
 
 	return nil
@@ -38,7 +38,14 @@ func (s *Server) buildConversationURL(id string) (u *url.URL, err error) {
 	return
 }
 
-func (s *Server) CreateConversation(ctx context.Context, participants []string, distinct bool, metadata common.Metadata) (*Conversation, error) {
+// CreateConversation creates a conversation.  The conversation ID can be set
+// with common.WithID, making retries with the same ID safe.
+func (s *Server) CreateConversation(ctx context.Context, participants []string, distinct bool, metadata common.Metadata, opts ...common.CreateOption) (*Conversation, error) {
+	ctx, span := s.startSpan(ctx, "server.CreateConversation")
+	defer span.End()
+
+	settings := common.NewCreateSettings(opts...)
+
 	// Create the request URL
 	u, err := s.buildConversationURL("")
 	if err != nil {
@@ -46,6 +53,7 @@ func (s *Server) CreateConversation(ctx context.Context, participants []string,
 	}
 
 	reqBody := map[string]interface{}{
+		"id":           common.LayerURL(common.ConversationsName, settings.ID),
 		"participants": participants,
 		"distinct":     distinct,
 		"metadata":     metadata,
@@ -57,16 +65,23 @@ func (s *Server) CreateConversation(ctx context.Context, participants []string,
 		return nil, fmt.Errorf("Error creating conversation request: %v", err)
 	}
 	req = req.WithContext(ctx)
+	req.Header.Set(common.IdempotencyKeyHeader, settings.ID)
 
 	// Send the request
 	res, err := s.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error creating conversation: %v", err)
+		return nil, fmt.Errorf("Error creating conversation: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		reqErr := s.conflictError(common.ResponseError(res))
+
+		// A conversation created by an earlier attempt of the same request
+		if existing, ok := reqErr.Data.(*Conversation); ok && existing.CreatedWith(settings.ID, participants, distinct) {
+			return existing, nil
+		}
+		return nil, reqErr
 	}
 
 	c := &Conversation{}
@@ -75,7 +90,25 @@ func (s *Server) CreateConversation(ctx context.Context, participants []string,
 	return c, err
 }
 
+// conflictError sets the conflicting conversation returned with a conflict
+// error as the error data
+func (s *Server) conflictError(reqErr common.RequestError) common.RequestError {
+	if !reqErr.Is(common.ErrConflict) {
+		return reqErr
+	}
+
+	var conversation *Conversation
+	if err := reqErr.DecodeData(&conversation); err == nil && conversation != nil {
+		conversation.Client = s
+		reqErr.Data = conversation
+	}
+	return reqErr
+}
+
 func (s *Server) Conversation(ctx context.Context, id string) (*Conversation, error) {
+	ctx, span := s.startSpan(ctx, "server.Conversation")
+	defer span.End()
+
 	// Create the request URL
 	u, err := s.buildConversationURL(id)
 	if err != nil {
@@ -91,12 +124,12 @@ func (s *Server) Conversation(ctx context.Context, id string) (*Conversation, er
 	// Send the request
 	res, err := s.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error deleting conversation: %v", err)
+		return nil, fmt.Errorf("Error deleting conversation: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		return nil, common.ResponseError(res)
 	}
 
 	c := &Conversation{}
@@ -109,6 +142,10 @@ func (c *Conversation) Delete(ctx context.Context) error {
 	if c.Client == nil {
 		return errors.New("Client not set in conversation")
 	}
+
+	ctx, span := c.Client.startSpan(ctx, "server.Conversation.Delete")
+	defer span.End()
+
 	// Create the request URL
 	u, err := c.Client.buildConversationURL(c.UUID())
 	if err != nil {
@@ -124,12 +161,12 @@ func (c *Conversation) Delete(ctx context.Context) error {
 	// Send the request
 	res, err := c.Client.transport.Do(req)
 	if err != nil {
-		return fmt.Errorf("Error deleting conversation: %v", err)
+		return fmt.Errorf("Error deleting conversation: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("Status code is %d", res.StatusCode)
+		return common.ResponseError(res)
 	}
 	return nil
 }
@@ -163,6 +200,10 @@ func (c *Conversation) UpdateParticipants(ctx context.Context, edits []Conversat
 	if c.Client == nil {
 		return errors.New("Client not set in conversation")
 	}
+
+	ctx, span := c.Client.startSpan(ctx, "server.Conversation.UpdateParticipants")
+	defer span.End()
+
 	// Create the request URL
 	u, err := c.Client.buildConversationURL(c.UUID())
 	if err != nil {
@@ -179,12 +220,12 @@ func (c *Conversation) UpdateParticipants(ctx context.Context, edits []Conversat
 	// Send the request
 	res, err := c.Client.transport.Do(req)
 	if err != nil {
-		return fmt.Errorf("Error creating conversation: %v", err)
+		return fmt.Errorf("Error creating conversation: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("Status code is %d", res.StatusCode)
+		return common.ResponseError(res)
 	}
 	return nil
 }
@@ -194,6 +235,9 @@ func (c *Conversation) UpdateMetadata(ctx context.Context, edits []ConversationE
 		return errors.New("Client not set in conversation")
 	}
 
+	ctx, span := c.Client.startSpan(ctx, "server.Conversation.UpdateMetadata")
+	defer span.End()
+
 	// Create the request URL
 	u, err := c.Client.buildConversationURL(c.UUID())
 	if err != nil {
@@ -210,12 +254,12 @@ func (c *Conversation) UpdateMetadata(ctx context.Context, edits []ConversationE
 	// Send the request
 	res, err := c.Client.transport.Do(req)
 	if err != nil {
-		return fmt.Errorf("Error creating conversation: %v", err)
+		return fmt.Errorf("Error creating conversation: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("Status code is %d", res.StatusCode)
+		return common.ResponseError(res)
 	}
 	return nil
 }
@@ -225,6 +269,9 @@ func (c *Conversation) MarkRead(ctx context.Context, userID string, msgIndex *ui
 		return 0, errors.New("Client not set in conversation")
 	}
 
+	ctx, span := c.Client.startSpan(ctx, "server.Conversation.MarkRead")
+	defer span.End()
+
 	u, err := url.Parse(strings.TrimSuffix("users/"+userID+"/conversations/"+c.UUID(), "/"))
 	if err != nil {
 		return 0, err
@@ -245,12 +292,12 @@ func (c *Conversation) MarkRead(ctx context.Context, userID string, msgIndex *ui
 	// Send the request
 	res, err := c.Client.transport.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("Error deleting conversation: %v", err)
+		return 0, fmt.Errorf("Error deleting conversation: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusAccepted {
-		return 0, fmt.Errorf("Status code is %d", res.StatusCode)
+		return 0, common.ResponseError(res)
 	}
 
 	resp := map[string]uint32{}
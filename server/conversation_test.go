@@ -2,9 +2,16 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/layerhq/go-client/common"
+	"github.com/layerhq/go-client/option"
 )
 
 func createConversation(c *Server) (*Conversation, error) {
@@ -137,3 +144,49 @@ func TestConversationMarkRead(t *testing.T) {
 
 	// TODO: implement once we can write messages
 }
+
+func TestCreateConversationRetry(t *testing.T) {
+	var ids []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ID string `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.Header.Get(common.IdempotencyKeyHeader) != common.UUIDFromLayerURL(body.ID) {
+			t.Errorf("Expected the idempotency key to match the ID %s", body.ID)
+		}
+		ids = append(ids, body.ID)
+
+		// Report the conversation as created by an earlier attempt
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, `{"id":"id_in_use","code":111,"message":"The requested Conversation already exists","data":{"id":"%s","distinct":false,"participants":[{"user_id":"test"},{"user_id":"test1"}]}}`, body.ID)
+	}))
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	c, err := NewClient(context.Background(), "app", option.WithBearerToken("token"), option.OverrideURL(u))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := "11111111-2222-4333-8444-555555555555"
+	convo, err := c.CreateConversation(context.Background(), []string{"test", "test1"}, false, common.Metadata{}, common.WithID(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if convo.UUID() != id || convo.Client != c {
+		t.Fatalf("Expected the existing conversation, got %+v", convo)
+	}
+
+	// A conflict with different participants is still an error
+	_, err = c.CreateConversation(context.Background(), []string{"other"}, false, common.Metadata{}, common.WithID(id))
+	if !errors.Is(err, common.ErrConflict) {
+		t.Fatalf("Expected a conflict error, got %v", err)
+	}
+
+	// Generated IDs differ between calls
+	c.CreateConversation(context.Background(), []string{"test"}, false, common.Metadata{})
+	if len(ids) != 3 || ids[2] == ids[1] {
+		t.Fatalf("Expected a generated ID, got %v", ids)
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -32,6 +33,9 @@ func (s *Server) buildIdentityURL(id string) (*url.URL, error) {
 
 // Identity gets the identity object for a user ID
 func (s *Server) Identity(ctx context.Context, userID string) (*common.Identity, error) {
+	ctx, span := s.startSpan(ctx, "server.Identity")
+	defer span.End()
+
 	// Create the request URL
 	u, err := s.buildIdentityURL(userID)
 	if err != nil {
@@ -47,16 +51,12 @@ func (s *Server) Identity(ctx context.Context, userID string) (*common.Identity,
 	// Send the request
 	res, err := s.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error getting identity: %v", err)
+		return nil, fmt.Errorf("Error getting identity: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("No identity exists for the specified user")
-	}
-
 	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		return nil, common.ResponseError(res)
 	}
 
 	// Parse the body
@@ -73,6 +73,9 @@ func (s *Server) Identity(ctx context.Context, userID string) (*common.Identity,
 }
 
 func (s *Server) CreateIdentity(ctx context.Context, identity *common.Identity) (*common.Identity, error) {
+	ctx, span := s.startSpan(ctx, "server.CreateIdentity")
+	defer span.End()
+
 	// Create the request URL
 	u, err := s.buildIdentityURL(identity.UserID)
 	if err != nil {
@@ -93,22 +96,21 @@ func (s *Server) CreateIdentity(ctx context.Context, identity *common.Identity)
 	// Send the request
 	res, err := s.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error creating identity: %v", err)
+		return nil, fmt.Errorf("Error creating identity: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusConflict {
-		return nil, fmt.Errorf("An identity for the specified user already exists")
-	}
-
 	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		return nil, common.ResponseError(res)
 	}
 
 	return identity, nil
 }
 
 func (s *Server) DeleteIdentity(ctx context.Context, userID string) error {
+	ctx, span := s.startSpan(ctx, "server.DeleteIdentity")
+	defer span.End()
+
 	// Create the request URL
 	u, err := s.buildIdentityURL(userID)
 	if err != nil {
@@ -124,22 +126,21 @@ func (s *Server) DeleteIdentity(ctx context.Context, userID string) error {
 	// Send the request
 	res, err := s.transport.Do(req)
 	if err != nil {
-		return fmt.Errorf("Error deleting identity: %v", err)
+		return fmt.Errorf("Error deleting identity: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("No identity exists for the specified user")
-	}
-
 	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
-		return fmt.Errorf("Status code is %d", res.StatusCode)
+		return common.ResponseError(res)
 	}
 
 	return nil
 }
 
 func (s *Server) UpdateIdentity(ctx context.Context, identity *common.Identity, upsert bool) (*common.Identity, error) {
+	ctx, span := s.startSpan(ctx, "server.UpdateIdentity")
+	defer span.End()
+
 	if identity.UserID == "" {
 		return nil, fmt.Errorf("UserID must be set on the Identity object")
 	}
@@ -147,10 +148,14 @@ func (s *Server) UpdateIdentity(ctx context.Context, identity *common.Identity,
 	// Get the existing identity
 	_, err := s.Identity(ctx, identity.UserID)
 	if err != nil {
+		if !errors.Is(err, common.ErrNotFound) {
+			return nil, err
+		}
 		if !upsert {
 			return nil, fmt.Errorf("Identity does not exist, cannot update")
 		}
 
+		s.logger().Debug("Identity does not exist, creating it", "user_id", identity.UserID)
 		_, err := s.CreateIdentity(ctx, identity)
 		if err != nil {
 			return nil, fmt.Errorf("Error creating identity")
@@ -219,15 +224,18 @@ func (s *Server) UpdateIdentity(ctx context.Context, identity *common.Identity,
 	}
 	req = req.WithContext(ctx)
 
+	// Set operations can be safely replayed, so allow the update to be retried
+	req.Header.Set(common.IdempotencyKeyHeader, idempotencyKey(query))
+
 	// Send the request
 	res, err := s.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error updating identity: %v", err)
+		return nil, fmt.Errorf("Error updating identity: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusNoContent {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		return nil, common.ResponseError(res)
 	}
 
 	updatedIdentity, err := s.Identity(ctx, identity.UserID)
@@ -23,6 +23,7 @@ type MessageSchedule struct {
 
 // MessageCreate contains detail on a new message
 type MessageCreate struct {
+	ID           string                      `json:"id,omitempty"`
 	SenderID     string                      `json:"sender_id"`
 	Parts        []*common.MessagePart       `json:"parts"`
 	Notification *common.MessageNotification `json:"notification,omitempty"`
@@ -50,19 +51,25 @@ func (convo *Conversation) buildMessageURL(id string) (u *url.URL, err error) {
 	return
 }
 
-// SendMessage sends a message to the server
-func (convo *Conversation) SendMessage(ctx context.Context, sender string, parts []*common.MessagePart, notification *common.MessageNotification, schedule *MessageSchedule) (*common.Message, error) {
+// SendMessage sends a message to the server.  The message ID can be set with
+// common.WithID, making retries with the same ID safe.
+func (convo *Conversation) SendMessage(ctx context.Context, sender string, parts []*common.MessagePart, notification *common.MessageNotification, schedule *MessageSchedule, opts ...common.CreateOption) (*common.Message, error) {
 	if convo.Client == nil {
 		return nil, errors.New("Client not set in conversation")
 	}
 
+	ctx, span := convo.Client.startSpan(ctx, "server.Conversation.SendMessage")
+	defer span.End()
+
 	// Build the URL
 	u, err := convo.buildMessageURL("")
 	if err != nil {
 		return nil, fmt.Errorf("Error building message URL: %v", err)
 	}
 
+	settings := common.NewCreateSettings(opts...)
 	mc := &MessageCreate{
+		ID:           common.LayerURL(common.MessagesName, settings.ID),
 		SenderID:     sender,
 		Parts:        parts,
 		Notification: notification,
@@ -79,21 +86,27 @@ func (convo *Conversation) SendMessage(ctx context.Context, sender string, parts
 		return nil, fmt.Errorf("Error creating request: %v", err)
 	}
 	req = req.WithContext(ctx)
+	req.Header.Set(common.IdempotencyKeyHeader, settings.ID)
 
 	// Send the request
 	res, err := convo.Client.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error sending request: %v", err)
+		return nil, fmt.Errorf("Error sending request: %w", err)
 	}
 	defer res.Body.Close()
 
 	switch {
-	case res.StatusCode == http.StatusConflict:
-		return nil, fmt.Errorf("Requested message already exists")
 	case res.StatusCode == http.StatusAccepted:
 		return nil, nil
 	case res.StatusCode != http.StatusCreated:
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		reqErr := common.ResponseError(res)
+
+		// A message sent by an earlier attempt of the same request
+		var existing *common.Message
+		if reqErr.Is(common.ErrConflict) && reqErr.DecodeData(&existing) == nil && existing.CreatedWith(settings.ID, parts) {
+			return existing, nil
+		}
+		return nil, reqErr
 	}
 
 	var message *common.Message
@@ -102,9 +115,9 @@ func (convo *Conversation) SendMessage(ctx context.Context, sender string, parts
 }
 
 // SendTextMessage is a helper function to send a single-part plaintext message
-func (convo *Conversation) SendTextMessage(ctx context.Context, sender string, message string, notification *common.MessageNotification) (*common.Message, error) {
+func (convo *Conversation) SendTextMessage(ctx context.Context, sender string, message string, notification *common.MessageNotification, opts ...common.CreateOption) (*common.Message, error) {
 	msg := plaintextMessage(message)
-	return convo.SendMessage(ctx, sender, msg.Parts, notification, nil)
+	return convo.SendMessage(ctx, sender, msg.Parts, notification, nil, opts...)
 }
 
 // SendMessage sends a message batch
@@ -112,6 +125,10 @@ func (convo *Conversation) SendMessageBatch(ctx context.Context, mc []MessageCre
 	if convo.Client == nil {
 		return errors.New("Client not set in conversation")
 	}
+
+	ctx, span := convo.Client.startSpan(ctx, "server.Conversation.SendMessageBatch")
+	defer span.End()
+
 	if len(mc) > 12 {
 		return errors.New("A maximum of 12 messages are supported")
 	}
@@ -140,12 +157,12 @@ func (convo *Conversation) SendMessageBatch(ctx context.Context, mc []MessageCre
 	// Send the request
 	res, err := convo.Client.transport.Do(req)
 	if err != nil {
-		return fmt.Errorf("Error sending request: %v", err)
+		return fmt.Errorf("Error sending request: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("Status is %d", res.StatusCode)
+		return common.ResponseError(res)
 	}
 
 	return nil
@@ -186,6 +203,9 @@ func (it *MessageIterator) Next() (*common.Message, error) {
 
 // MessagesFrom gets all messages on a conversation from the specified offset
 func (convo *Conversation) MessagesFrom(ctx context.Context, from string, pageSize int) ([]*common.Message, error) {
+	ctx, span := convo.Client.startSpan(ctx, "server.Conversation.MessagesFrom")
+	defer span.End()
+
 	// Build the URL
 	u, err := convo.buildMessageURL("")
 	if err != nil {
@@ -213,16 +233,16 @@ func (convo *Conversation) MessagesFrom(ctx context.Context, from string, pageSi
 	// Send the request
 	res, err := convo.Client.transport.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Error sending request: %v", err)
+		return nil, fmt.Errorf("Error sending request: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
-		return nil, fmt.Errorf("Status code is %d", res.StatusCode)
+		return nil, common.ResponseError(res)
 	}
 
 	var messages []*common.Message
-	err = json.NewDecoder(res.Body).Decode(messages)
+	err = json.NewDecoder(res.Body).Decode(&messages)
 	return messages, err
 }
 
@@ -246,6 +266,9 @@ func (convo *Conversation) Messages(ctx context.Context) (*MessageIterator, erro
 
 // DeleteMessage deletes a message
 func (convo *Conversation) DeleteMessage(ctx context.Context, messageID string) error {
+	ctx, span := convo.Client.startSpan(ctx, "server.Conversation.DeleteMessage")
+	defer span.End()
+
 	// Build the URL
 	u, err := convo.buildMessageURL(messageID)
 	if err != nil {
@@ -262,12 +285,12 @@ func (convo *Conversation) DeleteMessage(ctx context.Context, messageID string)
 	// Send the request
 	res, err := convo.Client.transport.Do(req)
 	if err != nil {
-		return fmt.Errorf("Error sending request: %v", err)
+		return fmt.Errorf("Error sending request: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("Status code is %d", res.StatusCode)
+		return common.ResponseError(res)
 	}
 
 	return nil
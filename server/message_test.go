@@ -2,7 +2,13 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+
+	"github.com/layerhq/go-client/option"
 )
 
 func TestCreateTextMessage(t *testing.T) {
@@ -26,3 +32,30 @@ func TestCreateTextMessage(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestMessagesFrom(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("from_id") != "layer:///messages/3" || q.Get("page_size") != "2" {
+			t.Errorf("Expected paging from message 3, got %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[{"id":"layer:///messages/2"},{"id":"layer:///messages/1"}]`)
+	}))
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	c, err := NewClient(context.Background(), "app", option.WithBearerToken("token"), option.OverrideURL(u))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	convo := &Conversation{Client: c}
+	convo.ID = "layer:///conversations/1"
+	messages, err := convo.MessagesFrom(context.Background(), "layer:///messages/3", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].ID != "layer:///messages/2" || messages[1].ID != "layer:///messages/1" {
+		t.Fatalf("Expected messages 2 and 1, got %+v", messages)
+	}
+}
@@ -3,9 +3,13 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/url"
+	"strings"
 
+	"github.com/layerhq/go-client/common"
 	"github.com/layerhq/go-client/option"
 	"github.com/layerhq/go-client/transport"
 )
@@ -22,26 +26,65 @@ type UpdateOperation struct {
 	Value     interface{} `json:"value"`
 }
 
-// NewClient creates a new Layer Server API client
+// Default Server API endpoint and version, which can be changed with options
+const (
+	DefaultBaseURL    = "https://api.layer.com"
+	DefaultAPIVersion = "3.0"
+)
+
+// NewClient creates a new Layer Server API client.  The endpoint defaults to
+// the Layer hosted API, and can be changed with option.OverrideURL.
 func NewClient(ctx context.Context, appID string, options ...option.ClientOption) (*Server, error) {
-	u, err := url.Parse(fmt.Sprintf("https://api.layer.com/apps/%s/", appID))
+	settings := option.NewDialSettings(options...)
+	appID = settings.ApplicationID(appID)
+
+	base := settings.BaseURL
+	if base == nil {
+		var err error
+		if base, err = url.Parse(DefaultBaseURL); err != nil {
+			return nil, fmt.Errorf("Error building base URL: %v", err)
+		}
+	}
+
+	// Resolve the application path relative to the base URL path
+	root := *base
+	if !strings.HasSuffix(root.Path, "/") {
+		root.Path += "/"
+	}
+	u, err := root.Parse(fmt.Sprintf("apps/%s/", appID))
 	if err != nil {
 		return nil, fmt.Errorf("Error building base URL: %v", err)
 	}
 
-	return NewClientWithURLs(ctx, u, appID, options...)
+	return newClient(ctx, u, appID, settings)
 }
 
-// NewTestClient
+// NewClientWithURLs creates a new Layer Server API client with a custom
+// application URL.  Most users will not ever need to use this functionality.
 func NewClientWithURLs(ctx context.Context, u *url.URL, appID string, options ...option.ClientOption) (*Server, error) {
+	return newClient(ctx, u, appID, option.NewDialSettings(options...))
+}
+
+// newClient creates a Server API client from dial settings built once from
+// the caller's options
+func newClient(ctx context.Context, u *url.URL, appID string, settings *common.DialSettings) (*Server, error) {
+	version := settings.APIVersion
+	if version == "" {
+		version = DefaultAPIVersion
+	}
 	headers := map[string][]string{
-		"Accept":       {"application/vnd.layer+json; version=3.0"},
+		"Accept":       {fmt.Sprintf("application/vnd.layer+json; version=%s", version)},
 		"Content-Type": {"application/json"},
 	}
+	option.WithDefaultHeaders(headers).Apply(settings)
 
-	options = append(options, option.WithHeaders(headers))
+	// Authenticate with the certificate API key unless a token is given
+	if c := settings.Certificate; c != nil && c.APIKey != "" && settings.BearerToken == "" && settings.BearerTokenSource == nil {
+		option.WithBearerToken(c.APIKey).Apply(settings)
+	}
+	appID = settings.ApplicationID(appID)
 
-	t, err := transport.NewHTTPTransport(ctx, appID, u, nil, options...)
+	t, err := transport.NewHTTPTransportWithSettings(ctx, appID, u, nil, settings)
 	if err != nil {
 		return nil, err
 	}
@@ -59,3 +102,26 @@ func NewClientWithURLs(ctx context.Context, u *url.URL, appID string, options ..
 func (c *Server) BaseURL() *url.URL {
 	return c.baseURL
 }
+
+// startSpan starts a tracing span for a Server API operation
+func (c *Server) startSpan(ctx context.Context, operation string) (context.Context, common.Span) {
+	return common.StartSpan(ctx, c.transport.DialSettings().Tracer, operation)
+}
+
+// logger returns the logger configured with option.WithLogger
+func (c *Server) logger() common.Logger {
+	return common.RedactLogger(c.transport.DialSettings().Logger)
+}
+
+// idempotencyKey derives an idempotency key from a request body, so replays
+// of the same request share a key
+func idempotencyKey(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// RateLimitStats returns the time spent waiting on the client-side rate
+// limiter by endpoint family, or nil if no rate limit is set
+func (c *Server) RateLimitStats() map[string]transport.RateLimitStats {
+	return c.transport.RateLimitStats()
+}
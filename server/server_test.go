@@ -2,10 +2,9 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"net/url"
 	"os"
 	"testing"
 
@@ -44,26 +43,57 @@ func createTestClient() (*Server, error) {
 		return nil, fmt.Errorf("LAYER_TESTING_CREDENTIALS path is not set")
 	}
 
-	data, err := ioutil.ReadFile(path)
+	ctx := context.Background()
+
+	return NewClient(ctx, "", option.AllowInsecure(), option.WithCertificateFile(path))
+}
+
+func TestCreateClientWithBearerToken(t *testing.T) {
+	_, err := createTestClient()
 	if err != nil {
-		return nil, err
+		t.Fatal(err)
 	}
-	var c *common.Certificate
-	err = json.Unmarshal(data, &c)
+}
+
+func TestNewClientWithEndpointOptions(t *testing.T) {
+	u, _ := url.Parse("https://eu.example.com/layer")
+	s, err := NewClient(context.Background(), "app",
+		option.WithBearerToken("token"),
+		option.OverrideURL(u),
+		option.WithAPIVersion("3.1"),
+	)
 	if err != nil {
-		return nil, err
+		t.Fatal(err)
 	}
 
-	ctx := context.Background()
+	if s.BaseURL().String() != "https://eu.example.com/layer/apps/app/" {
+		t.Fatalf("Unexpected base URL %s", s.BaseURL())
+	}
+	accept := s.transport.DialSettings().Headers["Accept"]
+	if len(accept) != 1 || accept[0] != "application/vnd.layer+json; version=3.1" {
+		t.Fatalf("Unexpected Accept header %v", accept)
+	}
+}
 
-	return NewClient(ctx, c.ApplicationID, option.AllowInsecure(), option.WithBearerToken(c.APIKey))
+// countingOption counts how many times it is applied
+type countingOption struct{ applied *int }
+
+func (o countingOption) Apply(s *common.DialSettings) {
+	*o.applied++
 }
 
-func TestCreateClientWithBearerToken(t *testing.T) {
-	_, err := createTestClient()
+func TestNewClientAppliesOptionsOnce(t *testing.T) {
+	applied := 0
+	_, err := NewClient(context.Background(), "app",
+		option.WithBearerToken("token"),
+		countingOption{&applied},
+	)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if applied != 1 {
+		t.Fatalf("Expected options to be applied once, got %d", applied)
+	}
 }
 
 func ExampleNewClient() {
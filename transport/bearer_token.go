@@ -4,14 +4,25 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
+
+	"github.com/layerhq/go-client/common"
 
 	"golang.org/x/net/context"
 )
 
+// bearerTokenTTL is how long a token from a bearer token source is used
+// before the source is consulted again
+const bearerTokenTTL = 5 * time.Minute
+
 type bearerTokenTransport struct {
 	baseURL   *url.URL
 	ctx       context.Context
 	token     string
+	source    common.BearerTokenSource
+	tokenMu   sync.Mutex
+	fetchedAt time.Time
 	userAgent string
 	headers   map[string][]string
 	base      http.RoundTripper
@@ -23,20 +34,69 @@ func (t *bearerTokenTransport) RoundTrip(req *http.Request) (*http.Response, err
 		return nil, fmt.Errorf("No transport specified")
 	}
 
-	// Build the new request
-	newReq := *req
-	newReq.WithContext(t.ctx)
-	newReq.Header = t.headers
-	for k, v := range req.Header {
-		newReq.Header[k] = v
+	token, err := t.bearerToken(req.Context(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := rt.RoundTrip(t.prepareRequest(req, token))
+	if err != nil || res.StatusCode != http.StatusUnauthorized || t.source == nil {
+		return res, err
+	}
+
+	// The token may have been rotated, so fetch the current token and replay
+	// the request once if it has changed
+	if req.Body != nil && req.GetBody == nil {
+		return res, nil
+	}
+	fresh, err := t.bearerToken(req.Context(), token)
+	if err != nil || fresh == token {
+		return res, nil
+	}
+	res.Body.Close()
+
+	replay := req.WithContext(req.Context())
+	if req.GetBody != nil {
+		replay.Body, err = req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("Error rebuilding request body: %v", err)
+		}
 	}
-	newReq.Header["User-Agent"] = []string{t.userAgent}
-	if t.token != "" {
-		newReq.Header["Authorization"] = []string{fmt.Sprintf("Bearer %s", t.token)}
+
+	return rt.RoundTrip(t.prepareRequest(replay, fresh))
+}
+
+// prepareRequest applies the headers and bearer token to a request
+func (t *bearerTokenTransport) prepareRequest(req *http.Request, token string) *http.Request {
+	authorization := ""
+	if token != "" {
+		authorization = fmt.Sprintf("Bearer %s", token)
 	}
+	newReq := prepareRequest(req, t.headers, t.userAgent, authorization)
 	if newReq.Method == "PATCH" {
-		newReq.Header["Content-Type"] = []string{fmt.Sprintf("application/vnd.layer-patch+json")}
+		newReq.Header.Set("Content-Type", "application/vnd.layer-patch+json")
 	}
+	return newReq
+}
+
+// bearerToken returns the cached token, consulting the token source once it
+// has expired or if the cached token is the rejected token
+func (t *bearerTokenTransport) bearerToken(ctx context.Context, rejected string) (string, error) {
+	t.tokenMu.Lock()
+	defer t.tokenMu.Unlock()
 
-	return rt.RoundTrip(&newReq)
+	if t.source == nil {
+		return t.token, nil
+	}
+	if t.token != "" && t.token != rejected && time.Since(t.fetchedAt) < bearerTokenTTL {
+		return t.token, nil
+	}
+
+	token, err := t.source.BearerToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Error getting bearer token: %w", err)
+	}
+	t.token = token
+	t.fetchedAt = time.Now()
+	return token, nil
 }
@@ -0,0 +1,84 @@
+package transport
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/layerhq/go-client/common"
+	"github.com/layerhq/go-client/option"
+
+	"golang.org/x/net/context"
+)
+
+func TestBearerTokenSource(t *testing.T) {
+	var mu sync.Mutex
+	valid := "key-1"
+	var bodies []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Authorization") != "Bearer "+valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	current := "key-1"
+	fetches := 0
+	source := common.BearerTokenSourceFunc(func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		return current, nil
+	})
+
+	u, _ := url.Parse(s.URL)
+	tr, err := NewHTTPTransport(context.Background(), "app", u, nil, option.WithBearerTokenSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func() int {
+		req, _ := http.NewRequest(http.MethodPost, s.URL+"/conversations", bytes.NewBufferString("payload"))
+		res, err := tr.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// Tokens are cached between requests
+	if send() != http.StatusOK || send() != http.StatusOK || fetches != 1 {
+		t.Fatalf("Expected the token to be cached, got %d fetches", fetches)
+	}
+
+	// Rotating the key refreshes the token and replays the request
+	mu.Lock()
+	valid, current = "key-2", "key-2"
+	mu.Unlock()
+	if status := send(); status != http.StatusOK {
+		t.Fatalf("Expected the replayed request to succeed, got %d", status)
+	}
+	if fetches != 2 || bodies[len(bodies)-1] != "payload" {
+		t.Fatalf("Expected a refresh and replay, got %d fetches and bodies %v", fetches, bodies)
+	}
+
+	// A rejected token that has not changed is not replayed
+	mu.Lock()
+	valid = "key-3"
+	mu.Unlock()
+	requests := len(bodies)
+	if status := send(); status != http.StatusUnauthorized || len(bodies) != requests+1 {
+		t.Fatalf("Expected a single rejected request, got %d after %d requests", status, len(bodies)-requests)
+	}
+}
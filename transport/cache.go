@@ -0,0 +1,257 @@
+package transport
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/layerhq/go-client/common"
+)
+
+// cacheEntry is a cached response body and its validators
+type cacheEntry struct {
+	key          string
+	path         string
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// cacheTransport revalidates repeated GET requests, serving cached bodies on
+// 304 Not Modified.  Mutating requests invalidate cached responses for the
+// same object.
+type cacheTransport struct {
+	config  common.ResponseCache
+	base    http.RoundTripper
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	size    int64
+
+	// generations counts invalidations, so that responses to requests sent
+	// before an invalidation are not cached
+	generations uint64
+}
+
+func newCacheTransport(config *common.ResponseCache, base http.RoundTripper) *cacheTransport {
+	c := *config
+	defaults := common.DefaultResponseCache()
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = defaults.MaxEntries
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = defaults.MaxBytes
+	}
+	if c.MaxEntryBytes <= 0 {
+		c.MaxEntryBytes = defaults.MaxEntryBytes
+	}
+
+	return &cacheTransport{
+		config:  c,
+		base:    base,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		if req.Method == http.MethodHead || req.Method == http.MethodOptions {
+			return t.base.RoundTrip(req)
+		}
+
+		// Invalidate before sending so that GET requests in flight are not
+		// cached, and after so that GET requests sent in the meantime are not
+		t.Invalidate(req.URL.Path)
+		res, err := t.base.RoundTrip(req)
+		t.Invalidate(req.URL.Path)
+		return res, err
+	}
+
+	// Conditional requests from the caller are passed through untouched
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	generation := t.generation()
+	entry := t.get(key)
+	if entry != nil {
+		req = req.Clone(req.Context())
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && entry != nil:
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		return cachedResponse(req, res, entry), nil
+	case res.StatusCode != http.StatusOK:
+		return res, nil
+	}
+
+	etag, lastModified := res.Header.Get("ETag"), res.Header.Get("Last-Modified")
+	if (etag == "" && lastModified == "") || strings.Contains(res.Header.Get("Cache-Control"), "no-store") {
+		t.remove(key)
+		return res, nil
+	}
+
+	// Buffer the body to cache it, unless it is too large
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, t.config.MaxEntryBytes+1))
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > t.config.MaxEntryBytes {
+		t.remove(key)
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), res.Body), res.Body}
+		return res, nil
+	}
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	t.put(&cacheEntry{
+		key:          key,
+		path:         req.URL.Path,
+		etag:         etag,
+		lastModified: lastModified,
+		header:       res.Header.Clone(),
+		body:         body,
+	}, generation)
+	return res, nil
+}
+
+// Invalidate removes cached responses for the object at the path, the
+// collections containing it and any objects below it
+func (t *cacheTransport) Invalidate(path string) {
+	path = strings.TrimSuffix(path, "/")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.generations++
+	for e := t.lru.Front(); e != nil; {
+		next := e.Next()
+		entryPath := strings.TrimSuffix(e.Value.(*cacheEntry).path, "/")
+		if entryPath == path || strings.HasPrefix(entryPath, path+"/") || strings.HasPrefix(path, entryPath+"/") {
+			t.removeElement(e)
+		}
+		e = next
+	}
+}
+
+// Purge removes all cached responses
+func (t *cacheTransport) Purge() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.generations++
+	t.lru.Init()
+	t.entries = make(map[string]*list.Element)
+	t.size = 0
+}
+
+// generation returns the number of invalidations so far
+func (t *cacheTransport) generation() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.generations
+}
+
+func (t *cacheTransport) get(key string) *cacheEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+	t.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry)
+}
+
+// put caches an entry unless the cache has been invalidated since the given
+// generation, in which case the entry may be stale
+func (t *cacheTransport) put(entry *cacheEntry, generation uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.generations != generation {
+		t.removeKey(entry.key)
+		return
+	}
+
+	t.removeKey(entry.key)
+	t.entries[entry.key] = t.lru.PushFront(entry)
+	t.size += int64(len(entry.body))
+
+	// Evict the least recently used entries beyond the limits
+	for t.lru.Len() > t.config.MaxEntries || t.size > t.config.MaxBytes {
+		t.removeElement(t.lru.Back())
+	}
+}
+
+func (t *cacheTransport) remove(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removeKey(key)
+}
+
+// removeKey removes the entry for a key, if any.  The caller must hold the
+// lock.
+func (t *cacheTransport) removeKey(key string) {
+	if e, ok := t.entries[key]; ok {
+		t.removeElement(e)
+	}
+}
+
+// removeElement removes an entry.  The caller must hold the lock.
+func (t *cacheTransport) removeElement(e *list.Element) {
+	entry := t.lru.Remove(e).(*cacheEntry)
+	delete(t.entries, entry.key)
+	t.size -= int64(len(entry.body))
+}
+
+// cacheKey identifies a cacheable request by URL and requested content type
+func cacheKey(req *http.Request) string {
+	return req.URL.String() + " " + req.Header.Get("Accept")
+}
+
+// cachedResponse builds a response from a cache entry, with any updated
+// headers from the 304 response
+func cachedResponse(req *http.Request, notModified *http.Response, entry *cacheEntry) *http.Response {
+	header := entry.header.Clone()
+	for k, v := range notModified.Header {
+		header[k] = v
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(entry.body)),
+		ContentLength: int64(len(entry.body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,154 @@
+package transport
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/layerhq/go-client/common"
+	"github.com/layerhq/go-client/option"
+
+	"golang.org/x/net/context"
+)
+
+func TestResponseCache(t *testing.T) {
+	version := 1
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("If-None-Match"))
+		if r.Method == http.MethodPatch {
+			version++
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		etag := fmt.Sprintf(`"v%d"`, version)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprintf(w, `{"version":%d}`, version)
+	}))
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	tr, err := NewHTTPTransport(context.Background(), "app", u, nil,
+		option.WithBearerToken("token"),
+		option.WithResponseCache(common.DefaultResponseCache()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path string) string {
+		req, _ := http.NewRequest(method, s.URL+path, nil)
+		res, err := tr.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		if method == http.MethodGet && res.StatusCode != http.StatusOK {
+			t.Fatalf("Expected cached responses to be served as 200, got %d", res.StatusCode)
+		}
+		return string(body)
+	}
+
+	if body := do(http.MethodGet, "/conversations/1"); body != `{"version":1}` {
+		t.Fatalf("Unexpected body %s", body)
+	}
+	if body := do(http.MethodGet, "/conversations/1"); body != `{"version":1}` {
+		t.Fatalf("Expected the cached body, got %s", body)
+	}
+
+	// Updates invalidate the cached object
+	do(http.MethodPatch, "/conversations/1")
+	if body := do(http.MethodGet, "/conversations/1"); body != `{"version":2}` {
+		t.Fatalf("Expected the updated body, got %s", body)
+	}
+
+	expected := []string{
+		"GET /conversations/1 ",
+		`GET /conversations/1 "v1"`,
+		"PATCH /conversations/1 ",
+		"GET /conversations/1 ",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Unexpected requests %q", requests)
+	}
+}
+
+func TestResponseCacheInvalidateInFlight(t *testing.T) {
+	sent, release := make(chan struct{}), make(chan struct{})
+	tr := newCacheTransport(common.DefaultResponseCache(), roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodGet {
+			close(sent)
+			<-release
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": {`"1"`}},
+			Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}))
+
+	// A GET sent before an update completes after it
+	get, _ := http.NewRequest(http.MethodGet, "https://api.layer.com/conversations/1", nil)
+	done := make(chan error)
+	go func() {
+		res, err := tr.RoundTrip(get)
+		if err == nil {
+			res.Body.Close()
+		}
+		done <- err
+	}()
+	<-sent
+
+	patch, _ := http.NewRequest(http.MethodPatch, "https://api.layer.com/conversations/1", nil)
+	if _, err := tr.RoundTrip(patch); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if tr.get(cacheKey(get)) != nil {
+		t.Fatal("Expected the response to the GET sent before the update not to be cached")
+	}
+}
+
+func TestResponseCacheLimits(t *testing.T) {
+	tr := newCacheTransport(&common.ResponseCache{MaxEntries: 2, MaxBytes: 10, MaxEntryBytes: 6}, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := strings.TrimPrefix(req.URL.Path, "/")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": {`"1"`}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	}))
+
+	for _, path := range []string{"/aaaa", "/bbbb", "/cccc", "/too-large"} {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.layer.com"+path, nil)
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		if string(body) != path[1:] {
+			t.Fatalf("Expected the full body for %s, got %s", path, body)
+		}
+	}
+
+	if tr.lru.Len() != 2 || tr.size != 8 {
+		t.Fatalf("Expected two entries within the limits, got %d entries of %d bytes", tr.lru.Len(), tr.size)
+	}
+	if tr.get(cacheKey(&http.Request{URL: &url.URL{Scheme: "https", Host: "api.layer.com", Path: "/aaaa"}, Header: http.Header{}})) != nil {
+		t.Fatal("Expected the least recently used entry to be evicted")
+	}
+}
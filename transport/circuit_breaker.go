@@ -0,0 +1,201 @@
+package transport
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+)
+
+// circuit tracks the state of a single host and endpoint family
+type circuit struct {
+	state       common.CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+type circuitKey struct {
+	host   string
+	family string
+}
+
+// circuitTransition records a state change to report once unlocked
+type circuitTransition struct {
+	key      circuitKey
+	from, to common.CircuitState
+}
+
+// circuitBreakerTransport fails requests fast while the circuit for their
+// host and endpoint family is open
+type circuitBreakerTransport struct {
+	config   common.CircuitBreaker
+	logger   common.Logger
+	base     http.RoundTripper
+	now      func() time.Time
+	mu       sync.Mutex
+	circuits map[circuitKey]*circuit
+}
+
+func newCircuitBreakerTransport(config *common.CircuitBreaker, base http.RoundTripper) *circuitBreakerTransport {
+	c := *config
+	defaults := common.DefaultCircuitBreaker()
+	if c.FailureRatio <= 0 {
+		c.FailureRatio = defaults.FailureRatio
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = defaults.MinRequests
+	}
+	if c.Window <= 0 {
+		c.Window = defaults.Window
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = defaults.OpenTimeout
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = defaults.HalfOpenProbes
+	}
+
+	return &circuitBreakerTransport{
+		config:   c,
+		base:     base,
+		now:      time.Now,
+		circuits: make(map[circuitKey]*circuit),
+	}
+}
+
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := circuitKey{host: req.URL.Host, family: common.EndpointFamily(req.URL.Path)}
+	probe, err := t.allow(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := t.base.RoundTrip(req)
+
+	// Requests abandoned by the caller say nothing about the endpoint
+	if err != nil && req.Context().Err() != nil {
+		t.release(key, probe)
+		return res, err
+	}
+	t.record(key, probe, err != nil || res.StatusCode >= http.StatusInternalServerError)
+	return res, err
+}
+
+// allow returns an error if the circuit is open, and whether the request is
+// a half-open probe
+func (t *circuitBreakerTransport) allow(key circuitKey) (bool, error) {
+	var transition *circuitTransition
+	defer func() { t.notify(transition) }()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	c, ok := t.circuits[key]
+	if !ok {
+		c = &circuit{windowStart: now}
+		t.circuits[key] = c
+	}
+
+	switch c.state {
+	case common.CircuitClosed:
+		if now.Sub(c.windowStart) >= t.config.Window {
+			c.windowStart = now
+			c.requests = 0
+			c.failures = 0
+		}
+		return false, nil
+	case common.CircuitOpen:
+		if wait := t.config.OpenTimeout - now.Sub(c.openedAt); wait > 0 {
+			return false, &common.CircuitOpenError{Host: key.host, Family: key.family, RetryAfter: wait}
+		}
+		transition = t.setState(key, c, common.CircuitHalfOpen, now)
+	}
+
+	// Half-open
+	if c.probes >= t.config.HalfOpenProbes {
+		return false, &common.CircuitOpenError{Host: key.host, Family: key.family}
+	}
+	c.probes++
+	return true, nil
+}
+
+// record counts the outcome of a request
+func (t *circuitBreakerTransport) record(key circuitKey, probe bool, failed bool) {
+	var transition *circuitTransition
+	defer func() { t.notify(transition) }()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	c := t.circuits[key]
+	switch {
+	case probe && c.state == common.CircuitHalfOpen:
+		if failed {
+			transition = t.setState(key, c, common.CircuitOpen, now)
+			return
+		}
+		c.successes++
+		if c.successes >= t.config.HalfOpenProbes {
+			transition = t.setState(key, c, common.CircuitClosed, now)
+		}
+	case !probe && c.state == common.CircuitClosed:
+		c.requests++
+		if failed {
+			c.failures++
+		}
+		if c.requests >= t.config.MinRequests && float64(c.failures)/float64(c.requests) >= t.config.FailureRatio {
+			transition = t.setState(key, c, common.CircuitOpen, now)
+		}
+	}
+}
+
+// release returns the probe slot of a request that was not counted
+func (t *circuitBreakerTransport) release(key circuitKey, probe bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c := t.circuits[key]; probe && c.state == common.CircuitHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// setState moves a circuit to a new state, resetting its counters.  The
+// caller must hold the lock.
+func (t *circuitBreakerTransport) setState(key circuitKey, c *circuit, state common.CircuitState, now time.Time) *circuitTransition {
+	transition := &circuitTransition{key: key, from: c.state, to: state}
+	c.state = state
+	c.windowStart = now
+	c.requests = 0
+	c.failures = 0
+	c.probes = 0
+	c.successes = 0
+	if state == common.CircuitOpen {
+		c.openedAt = now
+	}
+	return transition
+}
+
+// notify reports a state change to the logger and callback
+func (t *circuitBreakerTransport) notify(transition *circuitTransition) {
+	if transition == nil {
+		return
+	}
+	if t.logger != nil {
+		t.logger.Warn("Layer circuit breaker state changed",
+			"host", transition.key.host,
+			"family", transition.key.family,
+			"from", transition.from.String(),
+			"to", transition.to.String(),
+		)
+	}
+	if t.config.OnStateChange != nil {
+		t.config.OnStateChange(transition.key.host, transition.key.family, transition.from, transition.to)
+	}
+}
@@ -0,0 +1,135 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	status := http.StatusServiceUnavailable
+	calls := 0
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	})
+
+	var transitions []string
+	tr := newCircuitBreakerTransport(&common.CircuitBreaker{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		OpenTimeout:  time.Minute,
+		OnStateChange: func(host, family string, from, to common.CircuitState) {
+			transitions = append(transitions, fmt.Sprintf("%s %s %s->%s", host, family, from, to))
+		},
+	}, base)
+	tr.now = func() time.Time { return now }
+
+	send := func(path string) error {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.layer.com"+path, nil)
+		_, err := tr.RoundTrip(req)
+		return err
+	}
+
+	// Failures open the circuit for the endpoint family
+	for i := 0; i < 4; i++ {
+		if err := send("/conversations"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := send("/conversations/1")
+	if !errors.Is(err, common.ErrCircuitOpen) {
+		t.Fatalf("Expected the circuit to be open, got %v", err)
+	}
+	var openErr *common.CircuitOpenError
+	if !errors.As(err, &openErr) || openErr.Family != common.EndpointConversations || openErr.RetryAfter != time.Minute {
+		t.Fatalf("Unexpected error %+v", openErr)
+	}
+	if calls != 4 {
+		t.Fatalf("Expected requests to fail fast, got %d calls", calls)
+	}
+
+	// Other endpoint families are unaffected
+	if err := send("/identities/user"); err != nil {
+		t.Fatalf("Expected other endpoint families to be allowed, got %v", err)
+	}
+
+	// A failed probe reopens the circuit
+	now = now.Add(time.Minute)
+	if err := send("/conversations"); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("/conversations"); !errors.Is(err, common.ErrCircuitOpen) {
+		t.Fatalf("Expected the circuit to reopen, got %v", err)
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	status = http.StatusOK
+	if err := send("/conversations"); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("/conversations"); err != nil {
+		t.Fatalf("Expected the circuit to close, got %v", err)
+	}
+
+	expected := []string{
+		"api.layer.com conversations closed->open",
+		"api.layer.com conversations open->half-open",
+		"api.layer.com conversations half-open->open",
+		"api.layer.com conversations open->half-open",
+		"api.layer.com conversations half-open->closed",
+	}
+	if fmt.Sprint(transitions) != fmt.Sprint(expected) {
+		t.Fatalf("Unexpected transitions %v", transitions)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	now := time.Now()
+	release := make(chan struct{})
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		<-release
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	tr := newCircuitBreakerTransport(&common.CircuitBreaker{OpenTimeout: time.Second}, base)
+	tr.now = func() time.Time { return now }
+	key := circuitKey{host: "api.layer.com", family: common.EndpointMessages}
+	tr.circuits[key] = &circuit{state: common.CircuitOpen, openedAt: now.Add(-time.Second)}
+
+	// Only one probe is allowed while half-open
+	done := make(chan error)
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.layer.com/messages/1", nil)
+		_, err := tr.RoundTrip(req)
+		done <- err
+	}()
+	for {
+		tr.mu.Lock()
+		probes := tr.circuits[key].probes
+		tr.mu.Unlock()
+		if probes == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.layer.com/messages/2", nil)
+	if _, err := tr.RoundTrip(req); !errors.Is(err, common.ErrCircuitOpen) {
+		t.Fatalf("Expected a second probe to be rejected, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if tr.circuits[key].state != common.CircuitClosed {
+		t.Fatalf("Expected the circuit to close, got %s", tr.circuits[key].state)
+	}
+}
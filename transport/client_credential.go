@@ -1,6 +1,9 @@
 package transport
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"fmt"
 	"time"
 
@@ -9,34 +12,55 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-func localCredentialTokenFactory(credentials *common.ClientCredentials, nonce string) (token string, err error) {
-	if credentials.Key == nil {
-		return "", fmt.Errorf("No key data specified")
+// SignIdentityToken mints a Layer identity token for the credentials user and
+// nonce, signed with the active credentials key
+func SignIdentityToken(credentials *common.ClientCredentials, nonce string) (token string, err error) {
+	now := time.Now()
+	signingKey, err := credentials.SigningKey(now)
+	if err != nil {
+		return "", err
+	}
+
+	// Build the keypair from the key
+	key, err := common.ParsePrivateKey(signingKey.KeyPair.Private)
+	if err != nil {
+		return "", fmt.Errorf("Error getting private key from keypair - %v", err)
+	}
+	method, err := signingMethod(key)
+	if err != nil {
+		return "", err
 	}
 
 	// Set claims
 	claims := jwt.MapClaims{}
 	claims["iss"] = credentials.ProviderID
 	claims["prn"] = credentials.User
-	claims["iat"] = time.Now().Unix()
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(time.Hour * 72).Unix()
 	claims["nce"] = nonce
 
 	// Create a token
-	jwtToken := jwt.NewWithClaims(jwt.GetSigningMethod("RS256"), claims)
+	jwtToken := jwt.NewWithClaims(method, claims)
 
 	// Set header values
 	jwtToken.Header["typ"] = "JWT"
-	jwtToken.Header["alg"] = "RS256"
+	jwtToken.Header["alg"] = method.Alg()
 	jwtToken.Header["cty"] = "layer-eit;v=1"
-	jwtToken.Header["kid"] = credentials.Key.ID
-
-	// Build the keypair from the key
-	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(credentials.Key.KeyPair.Private))
-	if err != nil {
-		return "", fmt.Errorf("Error getting private key from keypair - %v", err)
-	}
+	jwtToken.Header["kid"] = signingKey.ID
 
 	// Sign and get the complete encoded token as a string
 	return jwtToken.SignedString(key)
 }
+
+// signingMethod returns the JWT signing method for a private key
+func signingMethod(key interface{}) (jwt.SigningMethod, error) {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		return jwt.SigningMethodES256, nil
+	case ed25519.PrivateKey:
+		return SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("Unsupported private key type %T", key)
+}
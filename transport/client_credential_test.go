@@ -0,0 +1,99 @@
+package transport
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func testKey(t *testing.T, id string, private crypto.Signer) *common.Key {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &common.Key{
+		ID: id,
+		KeyPair: &common.KeyPair{
+			Private: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		},
+	}
+}
+
+func TestLocalCredentialTokenFactory(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		private crypto.Signer
+		alg     string
+	}{
+		{rsaKey, "RS256"},
+		{ecKey, "ES256"},
+		{edKey, "EdDSA"},
+	}
+
+	for _, test := range tests {
+		credentials := &common.ClientCredentials{
+			ProviderID: "provider",
+			User:       "user",
+			Key:        testKey(t, "key", test.private),
+		}
+		signed, err := SignIdentityToken(credentials, "nonce")
+		if err != nil {
+			t.Fatalf("Error signing with %s: %v", test.alg, err)
+		}
+
+		token, err := jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+			return test.private.Public(), nil
+		})
+		if err != nil {
+			t.Fatalf("Error verifying %s token: %v", test.alg, err)
+		}
+		if token.Header["alg"] != test.alg || token.Header["kid"] != "key" {
+			t.Errorf("Unexpected header %v", token.Header)
+		}
+		if token.Claims.(jwt.MapClaims)["nce"] != "nonce" {
+			t.Errorf("Unexpected claims %v", token.Claims)
+		}
+	}
+}
+
+func TestLocalCredentialTokenFactoryKeyRing(t *testing.T) {
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	now := time.Now()
+	old := testKey(t, "old", oldKey)
+	old.NotAfter = now.Add(time.Hour)
+	rotated := testKey(t, "new", newKey)
+	rotated.NotBefore = now.Add(-time.Minute)
+
+	credentials := &common.ClientCredentials{
+		User: "user",
+		Keys: common.KeyRing{old, rotated},
+	}
+	signed, err := SignIdentityToken(credentials, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := new(jwt.Parser).ParseUnverified(signed, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != "new" || token.Header["alg"] != "EdDSA" {
+		t.Fatalf("Expected the rotated key to be used, got %v", token.Header)
+	}
+}
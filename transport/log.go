@@ -0,0 +1,42 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+)
+
+// logTransport logs a redacted summary of every request and response
+type logTransport struct {
+	logger common.Logger
+	base   http.RoundTripper
+}
+
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.base
+	if rt == nil {
+		return nil, fmt.Errorf("No transport specified")
+	}
+
+	start := time.Now()
+	res, err := rt.RoundTrip(req)
+	if err != nil {
+		t.logger.Warn("Layer request failed",
+			"method", req.Method,
+			"url", common.RedactURL(req.URL),
+			"duration", time.Since(start),
+			"error", err,
+		)
+		return res, err
+	}
+
+	t.logger.Debug("Layer request",
+		"method", req.Method,
+		"url", common.RedactURL(req.URL),
+		"status", res.StatusCode,
+		"duration", time.Since(start),
+	)
+	return res, err
+}
@@ -0,0 +1,194 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+// RateLimitStats reports the time requests spent waiting on the rate limiter
+type RateLimitStats struct {
+	// Requests is the number of requests that passed through the limiter
+	Requests int64
+
+	// Delayed is the number of requests that had to wait
+	Delayed int64
+
+	// WaitTime is the total time spent waiting
+	WaitTime time.Duration
+}
+
+// tokenBucket is a token bucket rate limiter.  Tokens may go negative, which
+// represents requests that have reserved a future token.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+func newTokenBucket(limit *common.RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait before
+// using it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token that was not used
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate <= 0 {
+		return
+	}
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// wait blocks until a token is available or the context is done, returning
+// the time spent waiting
+func (b *tokenBucket) wait(ctx context.Context) (time.Duration, error) {
+	delay := b.reserve(time.Now())
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.cancel()
+		return time.Since(start), ctx.Err()
+	case <-timer.C:
+		return time.Since(start), nil
+	}
+}
+
+// rateLimitTransport paces requests according to a global rate limit and
+// per endpoint family rate limits
+type rateLimitTransport struct {
+	global    *tokenBucket
+	endpoints map[string]*tokenBucket
+	stats     map[string]*RateLimitStats
+	statsMu   sync.Mutex
+	metrics   common.Metrics
+	base      http.RoundTripper
+}
+
+func newRateLimitTransport(global *common.RateLimit, endpoints map[string]*common.RateLimit, base http.RoundTripper) *rateLimitTransport {
+	t := &rateLimitTransport{
+		endpoints: make(map[string]*tokenBucket),
+		stats:     make(map[string]*RateLimitStats),
+		base:      base,
+	}
+	if global != nil {
+		t.global = newTokenBucket(global)
+	}
+	for family, limit := range endpoints {
+		if limit != nil {
+			t.endpoints[family] = newTokenBucket(limit)
+		}
+	}
+	return t
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.base
+	if rt == nil {
+		return nil, fmt.Errorf("No transport specified")
+	}
+
+	family := common.EndpointFamily(req.URL.Path)
+
+	var waited time.Duration
+	var taken []*tokenBucket
+	for _, b := range []*tokenBucket{t.global, t.endpoints[family]} {
+		if b == nil {
+			continue
+		}
+		d, err := b.wait(req.Context())
+		waited += d
+		if err != nil {
+			// The request is not sent, so return the tokens already taken
+			for _, tb := range taken {
+				tb.cancel()
+			}
+			t.record(family, waited)
+			return nil, err
+		}
+		taken = append(taken, b)
+	}
+	t.record(family, waited)
+
+	return rt.RoundTrip(req)
+}
+
+func (t *rateLimitTransport) record(family string, waited time.Duration) {
+	if t.metrics != nil && waited > 0 {
+		t.metrics.ObserveRateLimitWait(family, waited)
+	}
+
+	t.statsMu.Lock()
+	defer t.statsMu.Unlock()
+
+	s, ok := t.stats[family]
+	if !ok {
+		s = &RateLimitStats{}
+		t.stats[family] = s
+	}
+	s.Requests++
+	if waited > 0 {
+		s.Delayed++
+		s.WaitTime += waited
+	}
+}
+
+// Stats returns a snapshot of the rate limit statistics by endpoint family
+func (t *rateLimitTransport) Stats() map[string]RateLimitStats {
+	t.statsMu.Lock()
+	defer t.statsMu.Unlock()
+
+	stats := make(map[string]RateLimitStats, len(t.stats))
+	for family, s := range t.stats {
+		stats[family] = *s
+	}
+	return stats
+}
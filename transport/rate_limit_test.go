@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(&common.RateLimit{Rate: 10, Burst: 2})
+	now := b.last
+
+	if d := b.reserve(now); d != 0 {
+		t.Fatalf("Expected burst token without delay, got %v", d)
+	}
+	if d := b.reserve(now); d != 0 {
+		t.Fatalf("Expected burst token without delay, got %v", d)
+	}
+	if d := b.reserve(now); d != 100*time.Millisecond {
+		t.Fatalf("Expected 100ms delay, got %v", d)
+	}
+	if d := b.reserve(now.Add(100 * time.Millisecond)); d != 100*time.Millisecond {
+		t.Fatalf("Expected 100ms delay, got %v", d)
+	}
+}
+
+func TestTokenBucketContext(t *testing.T) {
+	b := newTokenBucket(&common.RateLimit{Rate: 0.1, Burst: 1})
+	b.reserve(time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := b.wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	tr := newRateLimitTransport(nil, map[string]*common.RateLimit{
+		common.EndpointMessages: {Rate: 50, Burst: 1},
+	}, http.DefaultTransport)
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest(http.MethodGet, s.URL+"/conversations/1/messages", nil)
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	stats := tr.Stats()[common.EndpointMessages]
+	if stats.Requests != 3 || stats.Delayed != 2 || stats.WaitTime < 30*time.Millisecond {
+		t.Fatalf("Unexpected rate limit stats %+v", stats)
+	}
+}
+
+func TestRateLimitTransportCancel(t *testing.T) {
+	tr := newRateLimitTransport(&common.RateLimit{Rate: 0.001, Burst: 2}, map[string]*common.RateLimit{
+		common.EndpointMessages: {Rate: 0.1, Burst: 1},
+	}, http.DefaultTransport)
+	tr.endpoints[common.EndpointMessages].reserve(time.Now())
+
+	// The request is cancelled waiting for the endpoint token
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/conversations/1/messages", nil)
+	if _, err := tr.RoundTrip(req.WithContext(ctx)); err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+
+	tr.global.mu.Lock()
+	tokens := tr.global.tokens
+	tr.global.mu.Unlock()
+	if tokens < 2 {
+		t.Fatalf("Expected the global token to be returned, got %v tokens", tokens)
+	}
+}
@@ -0,0 +1,96 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+// maxIdentityTokenResponse limits the size of identity provider responses
+const maxIdentityTokenResponse = 64 * 1024
+
+// remoteTokenFactory returns a token function that requests identity tokens
+// from a remote identity provider, retrying connection failures and
+// retryable response statuses until the context is done
+func remoteTokenFactory(p *common.RemoteTokenProvider, base http.RoundTripper) contextTokenFactory {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = 3 * time.Second
+	}
+	policy := p.RetryPolicy
+	if policy == nil {
+		policy = common.DefaultRetryPolicy()
+		policy.MaxAttempts = 3
+	}
+	client := &http.Client{Transport: base, Timeout: timeout}
+
+	return func(ctx context.Context, user, nonce string) (string, error) {
+		body, err := json.Marshal(common.IdentityTokenRequest{UserID: user, Nonce: nonce})
+		if err != nil {
+			return "", fmt.Errorf("Error creating identity token JSON: %v", err)
+		}
+
+		for attempt := 1; ; attempt++ {
+			token, err := requestIdentityToken(ctx, client, p, body)
+			if err == nil {
+				return token, nil
+			}
+
+			retry := isRetryableError(err)
+			if reqErr, ok := err.(common.RequestError); ok {
+				retry = policy.RetryableStatus(reqErr.StatusCode)
+			}
+			if !retry || attempt >= policy.MaxAttempts || ctx.Err() != nil {
+				return "", err
+			}
+
+			timer := time.NewTimer(policy.Backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return "", ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// requestIdentityToken makes a single identity token request.  Unsuccessful
+// responses are returned as a common.RequestError.
+func requestIdentityToken(ctx context.Context, client *http.Client, p *common.RemoteTokenProvider, body []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("Error creating identity token request: %v", err)
+	}
+	for k, v := range p.Headers {
+		req.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Error requesting identity token: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", common.ResponseError(res)
+	}
+
+	var data common.IdentityTokenResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxIdentityTokenResponse)).Decode(&data); err != nil {
+		return "", fmt.Errorf("Error parsing identity token JSON: %v", err)
+	}
+	if data.IdentityToken == "" {
+		return "", fmt.Errorf("Identity provider returned no identity token")
+	}
+	return data.IdentityToken, nil
+}
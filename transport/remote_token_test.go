@@ -0,0 +1,118 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+func TestRemoteTokenFactory(t *testing.T) {
+	attempts := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var req common.IdentityTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if req.UserID != "user" || req.Nonce != "nonce" || r.Header.Get("Authorization") != "Bearer caller" {
+			t.Errorf("Unexpected request %+v with headers %v", req, r.Header)
+		}
+		json.NewEncoder(w).Encode(common.IdentityTokenResponse{IdentityToken: "identity"})
+	}))
+	defer s.Close()
+
+	policy := common.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	tokenFunc := remoteTokenFactory(&common.RemoteTokenProvider{
+		URL:         s.URL,
+		Headers:     map[string][]string{"Authorization": {"Bearer caller"}},
+		RetryPolicy: policy,
+	}, http.DefaultTransport)
+
+	token, err := tokenFunc(context.Background(), "user", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "identity" || attempts != 2 {
+		t.Fatalf("Expected identity token after a retry, got %q after %d attempts", token, attempts)
+	}
+}
+
+func TestRemoteTokenFactoryTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer s.Close()
+
+	policy := common.DefaultRetryPolicy()
+	policy.MaxAttempts = 2
+	policy.InitialBackoff = time.Millisecond
+	tokenFunc := remoteTokenFactory(&common.RemoteTokenProvider{
+		URL:         s.URL,
+		Timeout:     10 * time.Millisecond,
+		RetryPolicy: policy,
+	}, http.DefaultTransport)
+
+	if _, err := tokenFunc(context.Background(), "user", "nonce"); err == nil {
+		t.Fatal("Expected the request to time out")
+	}
+}
+
+func TestRemoteTokenFactoryPermanentError(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusOK} {
+		attempts := 0
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(status)
+			w.Write([]byte(`{}`))
+		}))
+
+		policy := common.DefaultRetryPolicy()
+		policy.InitialBackoff = time.Millisecond
+		tokenFunc := remoteTokenFactory(&common.RemoteTokenProvider{
+			URL:         s.URL,
+			RetryPolicy: policy,
+		}, http.DefaultTransport)
+
+		_, err := tokenFunc(context.Background(), "user", "nonce")
+		s.Close()
+		if err == nil || attempts != 1 {
+			t.Fatalf("Expected a single failed attempt for status %d, got %v after %d attempts", status, err, attempts)
+		}
+	}
+}
+
+func TestRemoteTokenFactoryCancel(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	policy := common.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Minute
+	tokenFunc := remoteTokenFactory(&common.RemoteTokenProvider{
+		URL:         s.URL,
+		RetryPolicy: policy,
+	}, http.DefaultTransport)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := tokenFunc(ctx, "user", "nonce"); err != context.DeadlineExceeded {
+		t.Fatalf("Expected the context deadline to stop retries, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected retries to stop at the deadline, took %s", elapsed)
+	}
+}
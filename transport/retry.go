@@ -0,0 +1,105 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+)
+
+// retryTransport retries idempotent requests that fail with a transient
+// error, as described by a retry policy
+type retryTransport struct {
+	policy *common.RetryPolicy
+	logger common.Logger
+	base   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.base
+	if rt == nil {
+		return nil, fmt.Errorf("No transport specified")
+	}
+
+	// Requests are only retried if they are idempotent and their body can be
+	// recreated
+	if !common.IsIdempotent(req) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return rt.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.WithContext(ctx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("Error rebuilding request body: %v", err)
+			}
+			attemptReq.Body = body
+		}
+
+		if attempt > 1 {
+			common.SpanFromContext(ctx).SetAttribute(common.SpanAttributeRetries, attempt-1)
+		}
+		res, err := rt.RoundTrip(attemptReq)
+		if attempt >= t.policy.MaxAttempts {
+			return res, err
+		}
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if !isRetryableError(err) {
+				return res, err
+			}
+			wait = t.policy.Backoff(attempt)
+		case t.policy.RetryableStatus(res.StatusCode):
+			var ok bool
+			if wait, ok = common.RetryAfter(res.Header, time.Now()); !ok {
+				wait = t.policy.Backoff(attempt)
+			} else if t.policy.MaxBackoff > 0 && wait > t.policy.MaxBackoff {
+				// The server asked for a longer wait than the policy allows,
+				// so leave the response to the caller
+				return res, err
+			}
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		default:
+			return res, err
+		}
+
+		if t.logger != nil {
+			t.logger.Debug("Retrying Layer request",
+				"method", req.Method,
+				"url", common.RedactURL(req.URL),
+				"attempt", attempt+1,
+				"wait", wait,
+			)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryableError returns true for connection failures that are likely to
+// be transient
+func isRetryableError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
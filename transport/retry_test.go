@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+func newTestRetryTransport() *retryTransport {
+	return &retryTransport{
+		policy: &common.RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			StatusCodes:    []int{http.StatusServiceUnavailable},
+		},
+		base: http.DefaultTransport,
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("Expected request body to be replayed, got %q", body)
+		}
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	req, _ := http.NewRequest(http.MethodPatch, s.URL, bytes.NewBufferString("payload"))
+	req.Header.Set(common.IdempotencyKeyHeader, "key")
+	res, err := newTestRetryTransport().RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent || attempts != 3 {
+		t.Fatalf("Expected success after 3 attempts, got status %d after %d", res.StatusCode, attempts)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	var attempts int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL, bytes.NewBufferString("payload"))
+	res, err := newTestRetryTransport().RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || attempts != 1 {
+		t.Fatalf("Expected a single attempt, got status %d after %d", res.StatusCode, attempts)
+	}
+}
+
+func TestRetryAfterExceedsMaxBackoff(t *testing.T) {
+	var attempts int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	tr := newTestRetryTransport()
+	tr.policy.MaxBackoff = time.Second
+	req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || attempts != 1 {
+		t.Fatalf("Expected a single attempt, got status %d after %d", res.StatusCode, attempts)
+	}
+}
+
+func TestRetryContextCancelled(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+	start := time.Now()
+	if _, err := newTestRetryTransport().RoundTrip(req.WithContext(ctx)); err != context.DeadlineExceeded {
+		t.Fatalf("Expected the context deadline to stop retries, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected retries to stop at the deadline, took %s", elapsed)
+	}
+}
@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+// sessionTokenTransport authenticates requests with a session token that has
+// been minted elsewhere.  If a fallback token provider is configured it takes
+// over once the supplied session token is rejected.
+type sessionTokenTransport struct {
+	token     string
+	tokenMu   *sync.Mutex
+	fallback  *tokenProviderTransport
+	logger    common.Logger
+	ctx       context.Context
+	userAgent string
+	headers   map[string][]string
+	base      http.RoundTripper
+}
+
+func (t *sessionTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.base
+	if rt == nil {
+		return nil, fmt.Errorf("No transport specified")
+	}
+
+	token := t.sessionToken()
+	if token == "" {
+		if t.fallback != nil {
+			return t.fallback.RoundTrip(req)
+		}
+		return nil, fmt.Errorf("The session token has been rejected")
+	}
+
+	res, err := rt.RoundTrip(prepareRequest(req, t.headers, t.userAgent, sessionAuthorization(token)))
+	if err != nil || res.StatusCode != http.StatusUnauthorized || t.fallback == nil {
+		return res, err
+	}
+
+	// The supplied session has expired or been revoked, so discard it and
+	// replay the request through the fallback token provider if possible
+	if req.Body != nil && req.GetBody == nil {
+		return res, nil
+	}
+	res.Body.Close()
+	t.invalidate(token)
+	if t.logger != nil {
+		t.logger.Info("Session token rejected, falling back to the token provider")
+	}
+
+	replay := req.WithContext(req.Context())
+	if req.GetBody != nil {
+		replay.Body, err = req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("Error rebuilding request body: %v", err)
+		}
+	}
+
+	return t.fallback.RoundTrip(replay)
+}
+
+// sessionToken returns the supplied session token, or an empty string if it
+// has been rejected
+func (t *sessionTokenTransport) sessionToken() string {
+	t.tokenMu.Lock()
+	defer t.tokenMu.Unlock()
+	return t.token
+}
+
+// invalidate discards the supplied session token if it matches the token
+// that was rejected
+func (t *sessionTokenTransport) invalidate(token string) {
+	t.tokenMu.Lock()
+	defer t.tokenMu.Unlock()
+	if t.token == token {
+		t.token = ""
+	}
+}
+
+func (t *sessionTokenTransport) GetNonce(ctx context.Context) (string, error) {
+	if t.fallback != nil {
+		return t.fallback.GetNonce(ctx)
+	}
+	return "", fmt.Errorf("This transport does not support obtaining nonces.")
+}
+
+func (t *sessionTokenTransport) Token(ctx context.Context) (string, error) {
+	if token := t.sessionToken(); token != "" {
+		return token, nil
+	}
+	if t.fallback != nil {
+		return t.fallback.Token(ctx)
+	}
+	return "", fmt.Errorf("The session token has been rejected")
+}
+
+// RefreshToken discards the supplied session token and mints a new session
+// through the fallback token provider
+func (t *sessionTokenTransport) RefreshToken(ctx context.Context, stale string) (string, error) {
+	t.invalidate(stale)
+	if token := t.sessionToken(); token != "" {
+		return token, nil
+	}
+	if t.fallback != nil {
+		return t.fallback.RefreshToken(ctx, stale)
+	}
+	return "", fmt.Errorf("The session token has been rejected")
+}
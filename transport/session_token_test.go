@@ -0,0 +1,131 @@
+package transport
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestSessionToken(t *testing.T) {
+	s := newTestSessionServer(t)
+	defer s.Close()
+	s.valid = "preminted"
+
+	tr := &sessionTokenTransport{
+		token:     "preminted",
+		tokenMu:   &sync.Mutex{},
+		ctx:       context.Background(),
+		userAgent: "test",
+		headers:   map[string][]string{},
+		base:      http.DefaultTransport,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.URL+"/conversations", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", res.StatusCode)
+	}
+	if s.sessions != 0 {
+		t.Fatalf("Expected no sessions to be minted, got %d", s.sessions)
+	}
+
+	// Without a fallback the rejection is returned to the caller
+	s.expire()
+	req, _ = http.NewRequest(http.MethodGet, s.URL+"/conversations", nil)
+	res, err = tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %d", res.StatusCode)
+	}
+}
+
+func TestSessionTokenFallback(t *testing.T) {
+	s := newTestSessionServer(t)
+	defer s.Close()
+	s.valid = "preminted"
+
+	tr := &sessionTokenTransport{
+		token:     "preminted",
+		tokenMu:   &sync.Mutex{},
+		fallback:  newTestTokenProviderTransport(t, s),
+		ctx:       context.Background(),
+		userAgent: "test",
+		headers:   map[string][]string{},
+		base:      http.DefaultTransport,
+	}
+	s.expire()
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/conversations", bytes.NewBufferString("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected replayed request to succeed, got status %d", res.StatusCode)
+	}
+
+	token, err := tr.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "session-1" {
+		t.Fatalf("Expected fallback session token, got %s", token)
+	}
+}
+
+func TestSessionTokenRefresh(t *testing.T) {
+	s := newTestSessionServer(t)
+	defer s.Close()
+	s.valid = "preminted"
+
+	tr := &sessionTokenTransport{
+		token:     "preminted",
+		tokenMu:   &sync.Mutex{},
+		fallback:  newTestTokenProviderTransport(t, s),
+		ctx:       context.Background(),
+		userAgent: "test",
+		headers:   map[string][]string{},
+		base:      http.DefaultTransport,
+	}
+	ht := &HTTPTransport{Session: tr}
+
+	token, err := ht.RefreshSession(context.Background(), "preminted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "session-1" {
+		t.Fatalf("Expected a new session token, got %s", token)
+	}
+
+	// Refreshing a token that has already been replaced reuses the new session
+	token, err = ht.RefreshSession(context.Background(), "preminted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "session-1" || s.sessions != 1 {
+		t.Fatalf("Expected the current session token, got %s after %d sessions", token, s.sessions)
+	}
+
+	// Plain HTTP transports cannot refresh sessions
+	ht = &HTTPTransport{Session: httpTransport{}}
+	if _, err := ht.RefreshSession(context.Background(), "stale"); err == nil {
+		t.Fatal("Expected an error refreshing a session without a token provider")
+	}
+}
@@ -21,11 +21,17 @@ type ClientNonceRequest struct {
 
 type TokenFactory func(user, nonce string) (token string, err error)
 
+// contextTokenFactory is a TokenFactory that stops when the context is done
+type contextTokenFactory func(ctx context.Context, user, nonce string) (token string, err error)
+
 type tokenProviderTransport struct {
-	tokenFactory TokenFactory
+	tokenFactory contextTokenFactory
 	tokenTimeout time.Duration
 	token        string
 	tokenMu      *sync.Mutex
+	tokenStore   common.TokenStore
+	metrics      common.Metrics
+	logger       common.Logger
 	credentials  *common.ClientCredentials
 	baseURL      *url.URL
 	websocketURL *url.URL
@@ -41,37 +47,70 @@ func (t *tokenProviderTransport) RoundTrip(req *http.Request) (*http.Response, e
 		return nil, fmt.Errorf("No transport specified")
 	}
 
+	// Nonce and session requests are part of the authentication flow itself
+	// and are sent without a session token
+	if isSessionRequest(req) {
+		return rt.RoundTrip(prepareRequest(req, t.headers, t.userAgent, ""))
+	}
+
 	// See if need to generate a token
-	if t.token == "" && req.URL.Path != "/nonces" && req.URL.Path != "/sessions" {
-		_, err := t.Token(context.Background())
-		if err != nil {
-			return nil, err
-		}
+	token, err := t.Token(req.Context())
+	if err != nil {
+		return nil, err
 	}
 
-	// Build the new request
-	newReq := req
-	newReq.WithContext(t.ctx)
-	for k, v := range t.headers {
-		newReq.Header.Del(k)
-		for _, val := range v {
-			newReq.Header.Add(k, val)
-		}
+	res, err := rt.RoundTrip(prepareRequest(req, t.headers, t.userAgent, sessionAuthorization(token)))
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
 	}
-	for k, v := range req.Header {
-		newReq.Header.Del(k)
-		for _, val := range v {
-			newReq.Header.Add(k, val)
-		}
+
+	// The session has expired or been revoked.  Requests with a body can only
+	// be replayed if the body can be recreated.
+	if req.Body != nil && req.GetBody == nil {
+		return res, nil
 	}
-	newReq.Header.Del("User-Agent")
-	newReq.Header.Add("User-Agent", t.userAgent)
-	if t.token != "" {
-		newReq.Header.Del("Authorization")
-		newReq.Header.Add("Authorization", fmt.Sprintf("Layer session-token=\"%s\"", t.token))
+
+	// Layer includes a fresh nonce in the authentication challenge, which
+	// saves a round trip when minting the new session
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading authentication challenge: %v", err)
+	}
+
+	token, err = t.refreshToken(req.Context(), token, challengeNonce(body))
+	if err != nil {
+		return nil, err
+	}
+
+	// Replay the original request once with the new session
+	replay := req.WithContext(req.Context())
+	if req.GetBody != nil {
+		replay.Body, err = req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("Error rebuilding request body: %v", err)
+		}
 	}
 
-	return rt.RoundTrip(newReq)
+	return rt.RoundTrip(prepareRequest(replay, t.headers, t.userAgent, sessionAuthorization(token)))
+}
+
+// isSessionRequest returns true if the request is part of the session
+// authentication flow
+func isSessionRequest(req *http.Request) bool {
+	return req.URL.Path == "/nonces" || req.URL.Path == "/sessions"
+}
+
+// challengeNonce extracts the nonce from a Layer authentication challenge
+// body, returning an empty string if none is present
+func challengeNonce(body []byte) string {
+	resError := common.NewRequestError(http.StatusUnauthorized, body)
+	if data, ok := resError.Data.(map[string]interface{}); ok {
+		if nonce, ok := data["nonce"].(string); ok {
+			return nonce
+		}
+	}
+	return ""
 }
 
 func (t *tokenProviderTransport) GetNonce(ctx context.Context) (string, error) {
@@ -97,7 +136,7 @@ func (t *tokenProviderTransport) GetNonce(ctx context.Context) (string, error) {
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusCreated {
-		return "", fmt.Errorf("Error getting nonce: status code is %d", res.StatusCode)
+		return "", common.ResponseError(res)
 	}
 
 	// Parse the body
@@ -122,37 +161,140 @@ func (t *tokenProviderTransport) Token(ctx context.Context) (string, error) {
 	defer t.tokenMu.Unlock()
 
 	// Check if we have an existing valid token
+	if t.token == "" {
+		t.token = t.storedToken(ctx)
+	}
 	if t.token == "" {
 		var err error
-		t.token, err = t.getToken(ctx)
+		t.token, err = t.getToken(ctx, "")
 		if err != nil {
 			return "", err
 		}
+		t.storeToken(ctx, t.token)
 	}
 	return t.token, nil
 }
 
-func (t *tokenProviderTransport) getToken(ctx context.Context) (string, error) {
-	var err error
+// RefreshToken replaces a session token that has been rejected outside of
+// the transport, such as in a websocket handshake
+func (t *tokenProviderTransport) RefreshToken(ctx context.Context, stale string) (string, error) {
+	return t.refreshToken(ctx, stale, "")
+}
 
-	// Get a nonce
-	nonce, err := t.GetNonce(context.Background())
+// refreshToken replaces a session token that has been rejected by the server.
+// If another request has already replaced the stale token, the new token is
+// returned without minting another session.
+func (t *tokenProviderTransport) refreshToken(ctx context.Context, stale string, nonce string) (string, error) {
+	t.tokenMu.Lock()
+	defer t.tokenMu.Unlock()
+
+	if t.token != "" && t.token != stale {
+		return t.token, nil
+	}
+
+	// Another process sharing the token store may have already replaced the
+	// session
+	t.token = ""
+	if token := t.storedToken(ctx); token != "" && token != stale {
+		t.token = token
+		return t.token, nil
+	}
+	t.invalidateStoredToken(ctx)
+
+	token, err := t.getToken(ctx, nonce)
 	if err != nil {
 		return "", err
 	}
+	t.token = token
+	t.storeToken(ctx, t.token)
+	return t.token, nil
+}
+
+// storedToken returns the session token held in the token store, if any.
+// Token store failures are not fatal as a new session can always be minted.
+func (t *tokenProviderTransport) storedToken(ctx context.Context) string {
+	if t.tokenStore == nil || t.credentials == nil {
+		return ""
+	}
+	token, err := t.tokenStore.Get(ctx, t.credentials.ApplicationID, t.credentials.User)
+	if err != nil {
+		t.log().Warn("Error reading session from token store", "error", err)
+		return ""
+	}
+	return token
+}
+
+// storeToken saves a newly minted session token to the token store
+func (t *tokenProviderTransport) storeToken(ctx context.Context, token string) {
+	if t.tokenStore == nil || t.credentials == nil {
+		return
+	}
+	if err := t.tokenStore.Put(ctx, t.credentials.ApplicationID, t.credentials.User, token); err != nil {
+		t.log().Warn("Error saving session to token store", "error", err)
+	}
+}
+
+// invalidateStoredToken removes a rejected session token from the token store
+func (t *tokenProviderTransport) invalidateStoredToken(ctx context.Context) {
+	if t.tokenStore == nil || t.credentials == nil {
+		return
+	}
+	if err := t.tokenStore.Invalidate(ctx, t.credentials.ApplicationID, t.credentials.User); err != nil {
+		t.log().Warn("Error invalidating session in token store", "error", err)
+	}
+}
+
+// log returns the transport logger, which may not be set in tests
+func (t *tokenProviderTransport) log() common.Logger {
+	if t.logger == nil {
+		return common.NopLogger
+	}
+	return t.logger
+}
+
+// getToken mints a new session token, requesting a new nonce unless one is
+// provided
+func (t *tokenProviderTransport) getToken(ctx context.Context, nonce string) (string, error) {
+	start := time.Now()
+	token, err := t.mintSession(ctx, nonce)
+	if t.metrics != nil {
+		t.metrics.ObserveTokenMint(time.Since(start), err)
+	}
+	if err != nil {
+		t.log().Warn("Error minting Layer session", "duration", time.Since(start), "error", err)
+	} else {
+		t.log().Debug("Minted Layer session", "duration", time.Since(start))
+	}
+	return token, err
+}
+
+// mintSession runs the nonce, identity token and session flow
+func (t *tokenProviderTransport) mintSession(ctx context.Context, nonce string) (string, error) {
+	var err error
+
+	// Get a nonce
+	if nonce == "" {
+		nonce, err = t.GetNonce(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
 
 	// Get the signed token
-	tokenCh := make(chan string)
-	errCh := make(chan error)
+	tokenCh := make(chan string, 1)
+	errCh := make(chan error, 1)
 
 	if t.credentials == nil {
 		return "", fmt.Errorf("No username credentials have been specified")
 	}
 
-	go func(nonce string, user string, factory TokenFactory) {
-		token, err := factory(user, nonce)
+	factoryCtx, cancel := context.WithTimeout(ctx, t.tokenTimeout)
+	defer cancel()
+	go func(nonce string, user string, factory contextTokenFactory) {
+		token, err := factory(factoryCtx, user, nonce)
 		if err != nil {
 			errCh <- err
+			return
 		}
 		tokenCh <- token
 	}(nonce, t.credentials.User, t.tokenFactory)
@@ -162,7 +304,10 @@ func (t *tokenProviderTransport) getToken(ctx context.Context) (string, error) {
 		t.credentials.Token = token
 	case err := <-errCh:
 		return "", err
-	case <-time.After(t.tokenTimeout):
+	case <-factoryCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		return "", fmt.Errorf("Timeout in token factory")
 	}
 
@@ -198,12 +343,7 @@ func (t *tokenProviderTransport) getToken(ctx context.Context) (string, error) {
 	}
 
 	if res.StatusCode != http.StatusCreated {
-		var resError common.RequestError
-		err := json.Unmarshal(body, &resError)
-		if err == nil {
-			return "", resError
-		}
-		return "", fmt.Errorf("Error getting session - status code is %d", res.StatusCode)
+		return "", common.NewRequestError(res.StatusCode, body)
 	}
 
 	var data interface{}
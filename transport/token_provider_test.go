@@ -1,4 +1,141 @@
 package transport
 
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+// testSessionServer emulates the Layer nonce and session endpoints, issuing
+// sequentially numbered session tokens
+type testSessionServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	sessions int
+	valid    string
+	requests []string
+}
+
+func newTestSessionServer(t *testing.T) *testSessionServer {
+	s := &testSessionServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/nonces":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"nonce":"nonce"}`)
+		case "/sessions":
+			s.sessions++
+			s.valid = fmt.Sprintf("session-%d", s.sessions)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"session_token":"%s"}`, s.valid)
+		default:
+			body, _ := ioutil.ReadAll(r.Body)
+			s.requests = append(s.requests, string(body))
+			if r.Header.Get("Authorization") != fmt.Sprintf("Layer session-token=\"%s\"", s.valid) {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"id":"authentication_required","code":4,"message":"The session token has expired","data":{"nonce":"challenge"}}`)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	return s
+}
+
+// expire invalidates the current session token
+func (s *testSessionServer) expire() {
+	s.mu.Lock()
+	s.valid = ""
+	s.mu.Unlock()
+}
+
+func newTestTokenProviderTransport(t *testing.T, s *testSessionServer) *tokenProviderTransport {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tokenProviderTransport{
+		tokenFactory: func(ctx context.Context, user, nonce string) (string, error) {
+			return "identity-" + nonce, nil
+		},
+		tokenTimeout: time.Second,
+		tokenMu:      &sync.Mutex{},
+		credentials:  &common.ClientCredentials{User: "test"},
+		baseURL:      u,
+		ctx:          context.Background(),
+		userAgent:    "test",
+		headers:      map[string][]string{},
+		base:         http.DefaultTransport,
+	}
+}
+
 func TestToken(t *testing.T) {
+	s := newTestSessionServer(t)
+	defer s.Close()
+
+	tr := newTestTokenProviderTransport(t, s)
+	token, err := tr.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "session-1" {
+		t.Fatalf("Expected session-1, got %s", token)
+	}
+
+	// A second call should reuse the cached token
+	token, err = tr.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "session-1" || s.sessions != 1 {
+		t.Fatalf("Expected cached session token, got %s after %d sessions", token, s.sessions)
+	}
+}
+
+func TestTokenReauthentication(t *testing.T) {
+	s := newTestSessionServer(t)
+	defer s.Close()
+
+	tr := newTestTokenProviderTransport(t, s)
+	if _, err := tr.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s.expire()
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/conversations", bytes.NewBufferString("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected replayed request to succeed, got status %d", res.StatusCode)
+	}
+	if tr.token != "session-2" {
+		t.Fatalf("Expected session-2, got %s", tr.token)
+	}
+	if tr.credentials.Token != "identity-challenge" {
+		t.Fatalf("Expected the challenge nonce to be used, got %s", tr.credentials.Token)
+	}
+	if len(s.requests) != 2 || s.requests[1] != "payload" {
+		t.Fatalf("Expected the request body to be replayed, got %v", s.requests)
+	}
 }
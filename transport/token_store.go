@@ -0,0 +1,188 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/layerhq/go-client/common"
+
+	"golang.org/x/net/context"
+)
+
+func tokenStoreKey(appID, user string) string {
+	return appID + "/" + user
+}
+
+// MemoryTokenStore is a TokenStore that keeps session tokens in memory, which
+// allows clients within a single process to share sessions
+type MemoryTokenStore struct {
+	tokens map[string]string
+	sync.RWMutex
+}
+
+// NewMemoryTokenStore creates an empty in-memory token store
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: make(map[string]string),
+	}
+}
+
+func (s *MemoryTokenStore) Get(ctx context.Context, appID, user string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.tokens[tokenStoreKey(appID, user)], nil
+}
+
+func (s *MemoryTokenStore) Put(ctx context.Context, appID, user, token string) error {
+	s.Lock()
+	defer s.Unlock()
+	s.tokens[tokenStoreKey(appID, user)] = token
+	return nil
+}
+
+func (s *MemoryTokenStore) Invalidate(ctx context.Context, appID, user string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.tokens, tokenStoreKey(appID, user))
+	return nil
+}
+
+// FileTokenStore is a TokenStore that keeps session tokens in a JSON file,
+// allowing sessions to survive restarts and be shared between processes.  The
+// file is re-read on every lookup and replaced atomically on every update.
+// Updates hold an advisory lock on a ".lock" file next to the store so that
+// concurrent processes do not overwrite each other's tokens.  Platforms
+// without advisory locks only serialize updates within a process.
+type FileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileTokenStore creates a token store backed by the file at the given
+// path.  The file is created on the first update if it does not exist.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+func (s *FileTokenStore) Get(ctx context.Context, appID, user string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	return tokens[tokenStoreKey(appID, user)], nil
+}
+
+func (s *FileTokenStore) Put(ctx context.Context, appID, user, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	tokens[tokenStoreKey(appID, user)] = token
+	return s.save(tokens)
+}
+
+func (s *FileTokenStore) Invalidate(ctx context.Context, appID, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	key := tokenStoreKey(appID, user)
+	if _, ok := tokens[key]; !ok {
+		return nil
+	}
+	delete(tokens, key)
+	return s.save(tokens)
+}
+
+// lock takes the advisory lock guarding updates to the store, returning a
+// function that releases it
+func (s *FileTokenStore) lock() (func(), error) {
+	f, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Error locking token store: %v", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Error locking token store: %v", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+func (s *FileTokenStore) load() (map[string]string, error) {
+	tokens := make(map[string]string)
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading token store: %v", err)
+	}
+	if len(data) == 0 {
+		return tokens, nil
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("Error parsing token store JSON: %v", err)
+	}
+	return tokens, nil
+}
+
+func (s *FileTokenStore) save(tokens map[string]string) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("Error creating token store JSON: %v", err)
+	}
+
+	// Write to a temporary file and rename it so that other processes never
+	// observe a partially written store
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Error writing token store: %v", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("Error writing token store: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("Error writing token store: %v", err)
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("Error writing token store: %v", err)
+	}
+	return nil
+}
+
+var (
+	_ common.TokenStore = &MemoryTokenStore{}
+	_ common.TokenStore = &FileTokenStore{}
+)
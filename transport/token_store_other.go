@@ -0,0 +1,41 @@
+//go:build !unix
+
+package transport
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileLocks serializes access to lock files on platforms without advisory
+// file locks, which only protects stores within the same process
+var fileLocks = struct {
+	sync.Mutex
+	locks map[string]*sync.Mutex
+}{locks: make(map[string]*sync.Mutex)}
+
+func fileLock(f *os.File) *sync.Mutex {
+	path, err := filepath.Abs(f.Name())
+	if err != nil {
+		path = f.Name()
+	}
+	fileLocks.Lock()
+	defer fileLocks.Unlock()
+	l, ok := fileLocks.locks[path]
+	if !ok {
+		l = &sync.Mutex{}
+		fileLocks.locks[path] = l
+	}
+	return l
+}
+
+func lockFile(f *os.File) error {
+	fileLock(f).Lock()
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	fileLock(f).Unlock()
+	return nil
+}
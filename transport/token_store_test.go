@@ -0,0 +1,121 @@
+package transport
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layer-token-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	path := filepath.Join(dir, "tokens.json")
+	s := NewFileTokenStore(path)
+
+	token, err := s.Get(ctx, "app", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		t.Fatalf("Expected no token in an empty store, got %s", token)
+	}
+
+	if err := s.Put(ctx, "app", "user", "session"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second store sharing the file should see the token
+	token, err = NewFileTokenStore(path).Get(ctx, "app", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "session" {
+		t.Fatalf("Expected stored token, got %s", token)
+	}
+
+	if err := s.Invalidate(ctx, "app", "user"); err != nil {
+		t.Fatal(err)
+	}
+	token, err = s.Get(ctx, "app", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		t.Fatalf("Expected invalidated token to be removed, got %s", token)
+	}
+}
+
+func TestFileTokenStoreConcurrentUpdates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layer-token-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Separate stores stand in for separate processes sharing the file
+	ctx := context.Background()
+	path := filepath.Join(dir, "tokens.json")
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		go func(i int) {
+			errs <- NewFileTokenStore(path).Put(ctx, "app", fmt.Sprintf("user%d", i), "session")
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewFileTokenStore(path)
+	for i := 0; i < 20; i++ {
+		token, err := s.Get(ctx, "app", fmt.Sprintf("user%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "session" {
+			t.Fatalf("Expected a token for user%d, got %q", i, token)
+		}
+	}
+}
+
+func TestTokenFromStore(t *testing.T) {
+	s := newTestSessionServer(t)
+	defer s.Close()
+	s.valid = "stored"
+
+	ctx := context.Background()
+	store := NewMemoryTokenStore()
+	store.Put(ctx, "app", "test", "stored")
+
+	tr := newTestTokenProviderTransport(t, s)
+	tr.tokenStore = store
+	tr.credentials.ApplicationID = "app"
+
+	token, err := tr.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "stored" || s.sessions != 0 {
+		t.Fatalf("Expected stored token without minting a session, got %s after %d sessions", token, s.sessions)
+	}
+
+	// A rejected token should be replaced in the store
+	token, err = tr.refreshToken(ctx, "stored", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := store.Get(ctx, "app", "test")
+	if token != "session-1" || stored != "session-1" {
+		t.Fatalf("Expected refreshed token to be stored, got %s (stored %s)", token, stored)
+	}
+}
@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/layerhq/go-client/common"
+)
+
+// traceTransport annotates the span carried by the request context with the
+// HTTP method, status code and Layer request ID, starting a new span for
+// requests made outside of an API operation
+type traceTransport struct {
+	tracer common.Tracer
+	base   http.RoundTripper
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.base
+	if rt == nil {
+		return nil, fmt.Errorf("No transport specified")
+	}
+
+	ctx := req.Context()
+	span := common.SpanFromContext(ctx)
+	if !common.HasSpan(ctx) {
+		ctx, span = common.StartSpan(ctx, t.tracer, "HTTP "+req.Method)
+		defer span.End()
+		req = req.WithContext(ctx)
+	}
+
+	span.SetAttribute(common.SpanAttributeHTTPMethod, req.Method)
+	span.SetAttribute(common.SpanAttributeHTTPURL, req.URL.String())
+
+	res, err := rt.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return res, err
+	}
+
+	span.SetAttribute(common.SpanAttributeHTTPStatusCode, res.StatusCode)
+	for _, h := range common.RequestIDHeaders {
+		if id := res.Header.Get(h); id != "" {
+			span.SetAttribute(common.SpanAttributeRequestID, id)
+			break
+		}
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		span.SetError(fmt.Errorf("Status code is %d", res.StatusCode))
+	}
+
+	return res, err
+}
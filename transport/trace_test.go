@@ -0,0 +1,89 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/layerhq/go-client/common"
+	"github.com/layerhq/go-client/option"
+
+	"golang.org/x/net/context"
+)
+
+type testTracer struct {
+	spans []*testSpan
+	mu    sync.Mutex
+}
+
+type testSpan struct {
+	operation  string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (t *testTracer) StartSpan(ctx context.Context, operation string) (context.Context, common.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &testSpan{operation: operation, attributes: make(map[string]interface{})}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *testSpan) SetError(err error)                         { s.err = err }
+func (s *testSpan) End()                                       { s.ended = true }
+
+func TestTracing(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "request")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	tracer := &testTracer{}
+	u, _ := url.Parse(s.URL)
+	tr, err := NewHTTPTransport(context.Background(), "app", u, nil,
+		option.WithBearerToken("token"),
+		option.WithTracer(tracer),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, span := common.StartSpan(context.Background(), tracer, "server.Identity")
+	req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+	res, err := tr.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	span.End()
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("Expected the operation span to be annotated, got %d spans", len(tracer.spans))
+	}
+	ts := tracer.spans[0]
+	if ts.attributes[common.SpanAttributeHTTPMethod] != http.MethodGet ||
+		ts.attributes[common.SpanAttributeHTTPStatusCode] != http.StatusNotFound ||
+		ts.attributes[common.SpanAttributeRequestID] != "request" {
+		t.Fatalf("Unexpected span attributes %v", ts.attributes)
+	}
+	if ts.err == nil || !ts.ended {
+		t.Fatal("Expected an ended span with an error")
+	}
+
+	// Requests outside of an operation get their own span
+	req, _ = http.NewRequest(http.MethodGet, s.URL, nil)
+	res, err = tr.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if len(tracer.spans) != 2 || tracer.spans[1].operation != "HTTP GET" || !tracer.spans[1].ended {
+		t.Fatal("Expected a span for a request outside of an operation")
+	}
+}
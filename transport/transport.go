@@ -33,12 +33,65 @@ var DefaultTransport http.RoundTripper = &http.Transport{
 }
 
 type HTTPTransport struct {
-	Session HTTPSessionMinter
-	client  *http.Client
+	Session     HTTPSessionMinter
+	client      *http.Client
+	settings    *common.DialSettings
+	metrics     common.Metrics
+	rateLimiter *rateLimitTransport
+	cache       *cacheTransport
+	base        http.RoundTripper
 }
 
 func (t *HTTPTransport) Do(req *http.Request) (*http.Response, error) {
-	return t.client.Do(req)
+	if t.metrics == nil {
+		return t.client.Do(req)
+	}
+
+	start := time.Now()
+	res, err := t.client.Do(req)
+	statusCode := 0
+	if err == nil {
+		statusCode = res.StatusCode
+	}
+	t.metrics.ObserveRequest(common.EndpointFamily(req.URL.Path), req.Method, statusCode, time.Since(start))
+	return res, err
+}
+
+// DialSettings returns the settings the transport was created with
+func (t *HTTPTransport) DialSettings() *common.DialSettings {
+	return t.settings
+}
+
+// BaseTransport returns the underlying transport requests are sent with,
+// including any TLS settings, so that websocket connections can be made with
+// the same TLS configuration and proxy
+func (t *HTTPTransport) BaseTransport() http.RoundTripper {
+	return t.base
+}
+
+// RateLimitStats returns the time spent waiting on the client-side rate
+// limiter, by endpoint family.  It returns nil if no rate limit is set.
+func (t *HTTPTransport) RateLimitStats() map[string]RateLimitStats {
+	if t.rateLimiter == nil {
+		return nil
+	}
+	return t.rateLimiter.Stats()
+}
+
+// InvalidateCache removes cached responses for the object at the path, the
+// collections containing it and any objects below it.  Updates made through
+// the transport invalidate the cache automatically.
+func (t *HTTPTransport) InvalidateCache(path string) {
+	if t.cache != nil {
+		t.cache.Invalidate(path)
+	}
+}
+
+// PurgeCache removes all cached responses
+func (t *HTTPTransport) PurgeCache() {
+	if t.cache != nil {
+		t.cache.Purge()
+	}
 }
 
 type HTTPSessionMinter interface {
@@ -46,6 +99,22 @@ type HTTPSessionMinter interface {
 	Token(context.Context) (string, error)
 }
 
+// HTTPSessionRefresher is implemented by session minters that can replace a
+// session token which has been rejected
+type HTTPSessionRefresher interface {
+	RefreshToken(ctx context.Context, stale string) (string, error)
+}
+
+// RefreshSession discards a rejected session token and returns a new one.
+// If the session has already been replaced the current token is returned.
+func (t *HTTPTransport) RefreshSession(ctx context.Context, stale string) (string, error) {
+	refresher, ok := t.Session.(HTTPSessionRefresher)
+	if !ok {
+		return "", fmt.Errorf("This transport does not support refreshing sessions")
+	}
+	return refresher.RefreshToken(ctx, stale)
+}
+
 type httpTransport struct {
 	ctx          context.Context
 	userAgent    string
@@ -59,13 +128,36 @@ func (t httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if rt == nil {
 		return nil, fmt.Errorf("No transport specified")
 	}
-	newReq := *req
-	newReq.Header = t.headers
+	return rt.RoundTrip(prepareRequest(req, t.headers, t.userAgent, ""))
+}
+
+// prepareRequest returns a copy of the request with the default headers,
+// user agent and authorization applied.  Headers already set on the request
+// take precedence over the defaults, and the original request is not
+// modified.
+func prepareRequest(req *http.Request, headers map[string][]string, userAgent string, authorization string) *http.Request {
+	newReq := req.Clone(req.Context())
+	newReq.Header = make(http.Header, len(headers)+len(req.Header)+2)
+	for k, v := range headers {
+		newReq.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
 	for k, v := range req.Header {
-		newReq.Header[k] = v
+		newReq.Header[k] = append([]string(nil), v...)
 	}
-	newReq.Header["User-Agent"] = []string{t.userAgent}
-	return rt.RoundTrip(&newReq)
+	newReq.Header.Set("User-Agent", userAgent)
+	if authorization != "" {
+		newReq.Header.Set("Authorization", authorization)
+	}
+	return newReq
+}
+
+// sessionAuthorization returns the Authorization header value for a Layer
+// session token
+func sessionAuthorization(token string) string {
+	if token == "" {
+		return ""
+	}
+	return fmt.Sprintf("Layer session-token=\"%s\"", token)
 }
 
 func (t httpTransport) GetNonce(ctx context.Context) (string, error) {
@@ -77,25 +169,35 @@ func (t httpTransport) Token(ctx context.Context) (string, error) {
 }
 
 func NewHTTPTransport(ctx context.Context, appID string, baseURL *url.URL, websocketURL *url.URL, opts ...option.ClientOption) (*HTTPTransport, error) {
-	baseTransport := DefaultTransport
+	return NewHTTPTransportWithSettings(ctx, appID, baseURL, websocketURL, option.NewDialSettings(opts...))
+}
 
-	var o common.DialSettings
-	for _, opt := range opts {
-		opt.Apply(&o)
+// NewHTTPTransportWithSettings creates a transport from dial settings that
+// have already been built from options, so that options are only applied
+// once.  The settings are copied and not modified.
+func NewHTTPTransportWithSettings(ctx context.Context, appID string, baseURL *url.URL, websocketURL *url.URL, settings *common.DialSettings) (*HTTPTransport, error) {
+	o := *settings
+	if o.Err != nil {
+		return nil, o.Err
 	}
 
-	if o.UserAgent == "" {
-		o.UserAgent = fmt.Sprintf("Layer go-client version 0.1")
+	// Secrets are always redacted from log output
+	o.Logger = common.RedactLogger(o.Logger)
+
+	baseTransport, err := newBaseTransport(&o)
+	if err != nil {
+		return nil, err
 	}
 
-	if o.AllowInsecure {
-		baseTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	if o.UserAgent == "" {
+		o.UserAgent = fmt.Sprintf("Layer go-client version 0.1")
 	}
 
 	// Bearer token transport
-	if o.BearerToken != "" {
+	if o.BearerToken != "" || o.BearerTokenSource != nil {
 		t := &bearerTokenTransport{
 			token:     o.BearerToken,
+			source:    o.BearerTokenSource,
 			baseURL:   baseURL,
 			ctx:       ctx,
 			userAgent: o.UserAgent,
@@ -103,25 +205,81 @@ func NewHTTPTransport(ctx context.Context, appID string, baseURL *url.URL, webso
 			base:      baseTransport,
 		}
 
-		return &HTTPTransport{
-			client: &http.Client{Transport: t},
-		}, nil
+		return newHTTPTransport(&o, nil, t, baseTransport), nil
+	}
+
+	// Credentials from a certificate fill in any not given explicitly
+	appID = o.ApplicationID(appID)
+	if c := o.Certificate; c != nil && c.Key != nil && c.KeyPair != nil {
+		var credentials common.ClientCredentials
+		if o.ClientCredentials != nil {
+			credentials = *o.ClientCredentials
+		}
+		if credentials.ProviderID == "" {
+			credentials.ProviderID = c.ProviderID
+		}
+		if credentials.AccountID == "" {
+			credentials.AccountID = c.AccountID
+		}
+		if credentials.Key == nil && len(credentials.Keys) == 0 {
+			credentials.Key = c.Key
+		}
+
+		// A certificate does not name the user to authenticate as
+		if credentials.User == "" {
+			return nil, fmt.Errorf("Certificate credentials have no user, set one with option.WithCredentials")
+		}
+		o.ClientCredentials = &credentials
+	}
+
+	// A key ring replaces the key of the credentials
+	if len(o.KeyRing) > 0 {
+		var credentials common.ClientCredentials
+		if o.ClientCredentials != nil {
+			credentials = *o.ClientCredentials
+		}
+		credentials.Keys = o.KeyRing
+		o.ClientCredentials = &credentials
+	}
+	if o.ClientCredentials != nil && len(o.ClientCredentials.Keys) > 0 {
+		if err := o.ClientCredentials.Keys.Validate(); err != nil {
+			return nil, err
+		}
 	}
 
 	// Credentialed client
-	if o.ClientCredentials != nil && o.ClientCredentials.Key != nil {
+	if o.ClientCredentials != nil && (o.ClientCredentials.Key != nil || len(o.ClientCredentials.Keys) > 0) {
 		o.ClientCredentials.ApplicationID = appID
 		o.TokenFunc = func(user, nonce string) (token string, err error) {
-			return localCredentialTokenFactory(o.ClientCredentials, nonce)
+			return SignIdentityToken(o.ClientCredentials, nonce)
+		}
+	}
+
+	// Remote identity provider
+	var tokenFactory contextTokenFactory
+	if o.RemoteTokenProvider != nil && o.TokenFunc == nil {
+		tokenFactory = remoteTokenFactory(o.RemoteTokenProvider, baseTransport)
+		o.TokenFunc = func(user, nonce string) (token string, err error) {
+			return tokenFactory(context.Background(), user, nonce)
+		}
+	} else if tokenFunc := o.TokenFunc; tokenFunc != nil {
+		tokenFactory = func(ctx context.Context, user, nonce string) (string, error) {
+			return tokenFunc(user, nonce)
 		}
 	}
 
 	// Token provider transport
-	if o.TokenFunc != nil {
-		o.ClientCredentials.ApplicationID = appID
-		t := &tokenProviderTransport{
-			tokenFactory: o.TokenFunc,
+	var tp *tokenProviderTransport
+	if tokenFactory != nil {
+		if o.ClientCredentials != nil {
+			o.ClientCredentials.ApplicationID = appID
+		}
+		tp = &tokenProviderTransport{
+			tokenFactory: tokenFactory,
 			tokenTimeout: 10 * time.Second,
+			tokenStore:   o.TokenStore,
+			metrics:      o.Metrics,
+			logger:       o.Logger,
 			credentials:  o.ClientCredentials,
 			baseURL:      baseURL,
 			websocketURL: websocketURL,
@@ -130,12 +288,28 @@ func NewHTTPTransport(ctx context.Context, appID string, baseURL *url.URL, webso
 			headers:      o.Headers,
 			base:         baseTransport,
 		}
-		t.tokenMu = &sync.Mutex{}
+		tp.tokenMu = &sync.Mutex{}
+	}
+
+	// Session token transport, falling back to the token provider (if any)
+	// once the session token is rejected
+	if o.SessionToken != "" {
+		t := &sessionTokenTransport{
+			token:     o.SessionToken,
+			tokenMu:   &sync.Mutex{},
+			fallback:  tp,
+			logger:    o.Logger,
+			ctx:       ctx,
+			userAgent: o.UserAgent,
+			headers:   o.Headers,
+			base:      baseTransport,
+		}
+
+		return newHTTPTransport(&o, t, t, baseTransport), nil
+	}
 
-		return &HTTPTransport{
-			Session: t,
-			client:  &http.Client{Transport: t},
-		}, nil
+	if tp != nil {
+		return newHTTPTransport(&o, tp, tp, baseTransport), nil
 	}
 
 	// Fallback to a plain HTTP transport
@@ -146,8 +320,98 @@ func NewHTTPTransport(ctx context.Context, appID string, baseURL *url.URL, webso
 		base:      baseTransport,
 	}
 
+	return newHTTPTransport(&o, t, t, baseTransport), nil
+}
+
+// newBaseTransport returns the transport used to send requests, which is
+// DefaultTransport unless one is supplied in the dial settings.  Shared
+// transports are cloned rather than modified when TLS settings are applied.
+func newBaseTransport(o *common.DialSettings) (http.RoundTripper, error) {
+	baseTransport := DefaultTransport
+	if o.HTTPClient != nil && o.HTTPClient.Transport != nil {
+		baseTransport = o.HTTPClient.Transport
+	}
+	if o.BaseTransport != nil {
+		baseTransport = o.BaseTransport
+	}
+
+	if o.CustomTLS() {
+		t, ok := baseTransport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("TLS settings require an *http.Transport base transport")
+		}
+		t = t.Clone()
+		t.TLSClientConfig = o.TLSConfig(t.TLSClientConfig)
+		baseTransport = t
+	}
+
+	return baseTransport, nil
+}
+
+// newHTTPTransport wraps an authenticating transport with the request
+// policies configured in the dial settings
+func newHTTPTransport(o *common.DialSettings, session HTTPSessionMinter, rt, base http.RoundTripper) *HTTPTransport {
+	// Apply middleware so that the first middleware is the outermost
+	for i := len(o.Middleware) - 1; i >= 0; i-- {
+		rt = o.Middleware[i](rt)
+	}
+
+	var rateLimiter *rateLimitTransport
+	if o.RateLimit != nil || len(o.EndpointRateLimits) > 0 {
+		rateLimiter = newRateLimitTransport(o.RateLimit, o.EndpointRateLimits, rt)
+		rateLimiter.metrics = o.Metrics
+		rt = rateLimiter
+	}
+
+	if o.RetryPolicy != nil {
+		rt = &retryTransport{
+			policy: o.RetryPolicy,
+			logger: o.Logger,
+			base:   rt,
+		}
+	}
+
+	if o.CircuitBreaker != nil {
+		breaker := newCircuitBreakerTransport(o.CircuitBreaker, rt)
+		breaker.logger = o.Logger
+		rt = breaker
+	}
+
+	var cache *cacheTransport
+	if o.ResponseCache != nil {
+		cache = newCacheTransport(o.ResponseCache, rt)
+		rt = cache
+	}
+
+	if o.Tracer != nil {
+		rt = &traceTransport{
+			tracer: o.Tracer,
+			base:   rt,
+		}
+	}
+
+	if o.Logger != common.NopLogger {
+		rt = &logTransport{
+			logger: o.Logger,
+			base:   rt,
+		}
+	}
+
+	// Carry over the behaviour of a supplied client without modifying it
+	client := &http.Client{Transport: rt}
+	if o.HTTPClient != nil {
+		client.CheckRedirect = o.HTTPClient.CheckRedirect
+		client.Jar = o.HTTPClient.Jar
+		client.Timeout = o.HTTPClient.Timeout
+	}
+
 	return &HTTPTransport{
-		Session: t,
-		client:  &http.Client{Transport: t},
-	}, nil
+		Session:     session,
+		client:      client,
+		settings:    o,
+		metrics:     o.Metrics,
+		rateLimiter: rateLimiter,
+		cache:       cache,
+		base:        base,
+	}
 }
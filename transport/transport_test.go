@@ -0,0 +1,225 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/layerhq/go-client/common"
+	"github.com/layerhq/go-client/option"
+
+	"golang.org/x/net/context"
+)
+
+func TestAllowInsecureDoesNotModifyDefaultTransport(t *testing.T) {
+	u, _ := url.Parse("https://api.layer.com")
+	tr, err := NewHTTPTransport(context.Background(), "app", u, nil, option.AllowInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := DefaultTransport.(*http.Transport).TLSClientConfig; c != nil && c.InsecureSkipVerify {
+		t.Fatal("AllowInsecure modified DefaultTransport")
+	}
+
+	base := tr.client.Transport.(httpTransport).base.(*http.Transport)
+	if base == DefaultTransport || !base.TLSClientConfig.InsecureSkipVerify {
+		t.Fatal("Expected a cloned insecure base transport")
+	}
+}
+
+func TestWithBaseTransport(t *testing.T) {
+	custom := &http.Transport{}
+	u, _ := url.Parse("https://api.layer.com")
+	tr, err := NewHTTPTransport(context.Background(), "app", u, nil,
+		option.WithHTTPClient(&http.Client{Timeout: 5}),
+		option.WithBaseTransport(custom),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base := tr.client.Transport.(httpTransport).base; base != custom {
+		t.Fatal("Expected the supplied base transport to be used")
+	}
+	if tr.client.Timeout != 5 {
+		t.Fatal("Expected the supplied client timeout to be used")
+	}
+}
+
+func TestWithRootCAs(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	cert := s.TLS.Certificates[0]
+
+	u, _ := url.Parse(s.URL)
+	tr, err := NewHTTPTransport(context.Background(), "app", u, nil,
+		option.WithHeaders(map[string][]string{}),
+		option.WithRootCAs(pool),
+		option.WithClientCertificate(cert),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := tr.client.Transport.(httpTransport).base.(*http.Transport).TLSClientConfig
+	if config.RootCAs != pool || len(config.Certificates) != 1 {
+		t.Fatal("Expected root CAs and client certificate to be applied")
+	}
+	if DefaultTransport.(*http.Transport).TLSClientConfig != nil {
+		t.Fatal("TLS settings modified DefaultTransport")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+	res, err := tr.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
+
+func TestTLSConfigForWebsocket(t *testing.T) {
+	u, _ := url.Parse("https://api.layer.com")
+	tr, err := NewHTTPTransport(context.Background(), "app", u, nil, option.WithClientCertificate(tls.Certificate{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := tr.DialSettings()
+	if !settings.CustomTLS() || len(settings.TLSConfig(nil).Certificates) != 1 {
+		t.Fatal("Expected TLS settings to be available for websocket connections")
+	}
+}
+
+func TestWithMiddleware(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Signature") != "signed" {
+			t.Errorf("Expected middleware header, got %q", r.Header.Get("X-Signature"))
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	var order []string
+	middleware := func(name string) func(http.RoundTripper) http.RoundTripper {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				req = req.Clone(req.Context())
+				req.Header.Set("X-Signature", "signed")
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	u, _ := url.Parse(s.URL)
+	tr, err := NewHTTPTransport(context.Background(), "app", u, nil,
+		option.WithBearerToken("token"),
+		option.WithMiddleware(middleware("first"), middleware("second")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+	res, err := tr.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("Unexpected middleware order %v", order)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Fatal("The original request was modified")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) log(msg string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(append([]interface{}{msg}, args...)...))
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.log(msg, args...) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.log(msg, args...) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.log(msg, args...) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.log(msg, args...) }
+
+func TestWithLogger(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	l := &testLogger{}
+	u, _ := url.Parse(s.URL)
+	tr, err := NewHTTPTransport(context.Background(), "app", u, nil,
+		option.WithBearerToken("secret"),
+		option.WithLogger(l),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/conversations?session_token=abc123", nil)
+	res, err := tr.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if len(l.lines) != 1 {
+		t.Fatalf("Expected one request summary, got %v", l.lines)
+	}
+	if !strings.Contains(l.lines[0], "/conversations") || !strings.Contains(l.lines[0], "200") {
+		t.Errorf("Expected the path and status to be logged, got %q", l.lines[0])
+	}
+	if strings.Contains(l.lines[0], "abc123") || strings.Contains(l.lines[0], "secret") {
+		t.Errorf("Expected secrets to be redacted, got %q", l.lines[0])
+	}
+}
+
+func TestCertificateRequiresUser(t *testing.T) {
+	u, _ := url.Parse("https://api.layer.com")
+	certificate := &common.Certificate{
+		Key:        &common.Key{ID: "key", KeyPair: &common.KeyPair{}},
+		ProviderID: "provider",
+	}
+
+	settings := &common.DialSettings{Certificate: certificate}
+	if _, err := NewHTTPTransportWithSettings(context.Background(), "app", u, nil, settings); err == nil || !strings.Contains(err.Error(), "no user") {
+		t.Fatalf("Expected an error for certificate credentials without a user, got %v", err)
+	}
+
+	settings.ClientCredentials = &common.ClientCredentials{User: "user"}
+	tr, err := NewHTTPTransportWithSettings(context.Background(), "app", u, nil, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials := tr.DialSettings().ClientCredentials; credentials.User != "user" || credentials.ProviderID != "provider" {
+		t.Fatalf("Expected the certificate credentials for the user, got %+v", credentials)
+	}
+}